@@ -3,8 +3,11 @@ package s3gen
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // contentHash computes a SHA256 hash of the resource's content.
@@ -38,31 +41,9 @@ func (h *DefaultAssetHandler) HandleAssets(site *Site, res *Resource, assets []*
 	var mappings []AssetMapping
 
 	for _, asset := range assets {
-		var destPath string
-
-		if res.IsParametric {
-			// Parametric pages: assets go to shared folder with content-hash
-			hash := ContentHashShort(asset)
-			destPath = filepath.Join(site.SharedAssetsDir, hash, filepath.Base(asset.FullPath))
-		} else {
-			// Non-parametric: co-locate with output
-			// Determine output directory based on resource path
-			respath := res.RelPath()
-			if respath == "" {
-				continue
-			}
-
-			ext := filepath.Ext(respath)
-			rem := respath[:len(respath)-len(ext)]
-
-			var destDir string
-			if res.IsIndex {
-				destDir = filepath.Dir(respath)
-			} else {
-				destDir = rem
-			}
-
-			destPath = filepath.Join(destDir, filepath.Base(asset.FullPath))
+		destPath := assetDestPath(site, res, asset)
+		if destPath == "" {
+			continue
 		}
 
 		mappings = append(mappings, AssetMapping{
@@ -81,6 +62,10 @@ func GetAssetURL(site *Site, res *Resource, filename string) string {
 	// Check if the file is in the resource's assets
 	for _, asset := range res.Assets {
 		if filepath.Base(asset.FullPath) == filename {
+			// Use the fingerprinted name if the asset was fingerprinted
+			if fp, ok := site.fingerprintOf(assetDestPath(site, res, asset)); ok {
+				filename = filepath.Base(fp)
+			}
 			if res.IsParametric {
 				// Shared assets path
 				hash := ContentHashShort(asset)
@@ -93,3 +78,150 @@ func GetAssetURL(site *Site, res *Resource, filename string) string {
 	// Fallback to static folder
 	return site.PathPrefix + "/static/" + filename
 }
+
+// assetDestPath returns where a co-located asset of res is written, relative
+// to the output directory. Assets of parametric pages go to a shared folder
+// keyed by content hash; all others are placed next to the page's output.
+// Index and parametric pages are detected from the file name as well so this
+// can be used before the page has been loaded by its rule.
+func assetDestPath(site *Site, res *Resource, asset *Resource) string {
+	if res.IsParametric || isParametricPath(res.FullPath) {
+		// Parametric pages: assets go to shared folder with content-hash
+		hash := ContentHashShort(asset)
+		return filepath.Join(site.SharedAssetsDir, hash, filepath.Base(asset.FullPath))
+	}
+
+	// Non-parametric: co-locate with output
+	respath := res.RelPath()
+	if respath == "" {
+		return ""
+	}
+
+	ext := filepath.Ext(respath)
+	rem := respath[:len(respath)-len(ext)]
+
+	var destDir string
+	if res.IsIndex || isIndexPath(res.FullPath) {
+		destDir = filepath.Dir(respath)
+	} else {
+		destDir = rem
+	}
+	return filepath.Join(destDir, filepath.Base(asset.FullPath))
+}
+
+// fingerprintedPath inserts a content hash before the extension of a path,
+// eg css/app.css -> css/app.3f9a2c1e.css.
+func fingerprintedPath(path string, hash string) string {
+	ext := filepath.Ext(path)
+	return path[:len(path)-len(ext)] + "." + hash + ext
+}
+
+// recordFingerprint adds an entry to the asset manifest. Both paths are
+// relative to the output directory. The file of a previous fingerprint of
+// the same asset is removed.
+func (s *Site) recordFingerprint(original string, fingerprinted string) {
+	if s.assetManifest == nil {
+		s.assetManifest = map[string]string{}
+	}
+	original = strings.TrimPrefix(filepath.ToSlash(original), "/")
+	fingerprinted = strings.TrimPrefix(filepath.ToSlash(fingerprinted), "/")
+	for _, manifest := range []map[string]string{s.assetManifest, s.oldAssetManifest} {
+		if old, ok := manifest[original]; ok && old != fingerprinted {
+			s.removeAssetFile(old)
+		}
+	}
+	delete(s.oldAssetManifest, original)
+	s.assetManifest[original] = fingerprinted
+}
+
+// resetAssetManifest starts a new asset manifest for a full build. The
+// last manifest (read from AssetManifestPath on the first build) is kept
+// so the files of assets that changed or were removed can be deleted.
+func (s *Site) resetAssetManifest() {
+	s.oldAssetManifest = s.assetManifest
+	if len(s.oldAssetManifest) == 0 {
+		if data, err := os.ReadFile(filepath.Join(s.OutputDir, s.AssetManifestPath)); err == nil {
+			json.Unmarshal(data, &s.oldAssetManifest)
+		}
+	}
+	s.assetManifest = map[string]string{}
+}
+
+// pruneAssetManifest removes the fingerprinted files of the assets of the
+// last full build that were not built again.
+func (s *Site) pruneAssetManifest() {
+	for _, old := range s.oldAssetManifest {
+		s.removeAssetFile(old)
+	}
+	s.oldAssetManifest = nil
+}
+
+func (s *Site) removeAssetFile(relpath string) {
+	if err := os.Remove(filepath.Join(s.OutputDir, filepath.FromSlash(relpath))); err != nil && !os.IsNotExist(err) {
+		log.Println("Could not remove old asset: ", relpath, err)
+	}
+}
+
+// fingerprintOf returns the fingerprinted path recorded for an output path.
+func (s *Site) fingerprintOf(relpath string) (string, bool) {
+	fp, ok := s.assetManifest[strings.TrimPrefix(filepath.ToSlash(relpath), "/")]
+	return fp, ok
+}
+
+// fingerprintTarget renames a generated target to its fingerprinted name and
+// returns the resource for the renamed file. Targets that were not written
+// (eg by commands that write elsewhere) are returned as is.
+func (s *Site) fingerprintTarget(target *Resource) (*Resource, error) {
+	relpath, err := filepath.Rel(s.OutputDir, target.FullPath)
+	if err != nil || strings.HasPrefix(relpath, "..") {
+		return target, nil
+	}
+	if _, err := os.Stat(target.FullPath); err != nil {
+		return target, nil
+	}
+
+	hash := ContentHashShort(target)
+	if hash == "" {
+		return target, nil
+	}
+	destpath := fingerprintedPath(target.FullPath, hash)
+	if err := os.Rename(target.FullPath, destpath); err != nil {
+		return target, err
+	}
+	s.recordFingerprint(relpath, fingerprintedPath(relpath, hash))
+
+	fpres := s.GetResource(destpath)
+	fpres.Source = target.Source
+	return fpres, nil
+}
+
+// AssetManifest returns the mapping of original asset paths to their
+// fingerprinted paths, both relative to the output directory.
+func (s *Site) AssetManifest() map[string]string {
+	return s.assetManifest
+}
+
+// writeAssetManifest writes the asset manifest as JSON to AssetManifestPath.
+func (s *Site) writeAssetManifest() error {
+	s.pruneAssetManifest()
+	data, err := json.MarshalIndent(s.assetManifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(s.OutputDir, s.AssetManifestPath)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// Asset returns the URL for a built asset given its path relative to the
+// output directory (eg "css/app.css"). When FingerprintAssets is enabled the
+// fingerprinted path is looked up in the asset manifest. PathPrefix is applied.
+func (s *Site) Asset(path string) string {
+	relpath := strings.TrimPrefix(filepath.ToSlash(path), "/")
+	if fp, ok := s.fingerprintOf(relpath); ok {
+		relpath = fp
+	}
+	return s.PathRelUrl("/" + relpath)
+}
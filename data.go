@@ -0,0 +1,202 @@
+package s3gen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// dataDecoders parse the data files of the data dir by extension.
+var dataDecoders = map[string]func([]byte) (any, error){
+	".json": func(b []byte) (out any, err error) {
+		err = json.Unmarshal(b, &out)
+		return
+	},
+	".yaml": decodeYaml,
+	".yml":  decodeYaml,
+	".toml": func(b []byte) (any, error) {
+		out := map[string]any{}
+		err := toml.Unmarshal(b, &out)
+		return out, err
+	},
+	".csv": decodeCsv,
+}
+
+func decodeYaml(b []byte) (out any, err error) {
+	err = yaml.Unmarshal(b, &out)
+	return
+}
+
+// decodeCsv parses a CSV file with a header row into a list of rows, each a
+// map of the header fields to the row's values.
+func decodeCsv(b []byte) (any, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return []any{}, err
+	}
+	header := records[0]
+	out := []any{}
+	for _, record := range records[1:] {
+		row := map[string]any{}
+		for i, field := range header {
+			if i < len(record) {
+				row[field] = record[i]
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// normalizeData converts the map[any]any values YAML decodes into
+// map[string]any so that all data can be accessed the same way.
+func normalizeData(val any) any {
+	switch v := val.(type) {
+	case map[any]any:
+		out := map[string]any{}
+		for key, value := range v {
+			out[fmt.Sprint(key)] = normalizeData(value)
+		}
+		return out
+	case map[string]any:
+		for key, value := range v {
+			v[key] = normalizeData(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeData(value)
+		}
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = normalizeData(value)
+		}
+		return out
+	}
+	return val
+}
+
+// isDataPath returns true if a path is in the data dir. The data dir may
+// be relative while the watcher reports absolute paths, so both are made
+// absolute first.
+func (s *Site) isDataPath(path string) bool {
+	if s.DataDir == "" {
+		return false
+	}
+	datadir, err := filepath.Abs(s.DataDir)
+	if err != nil {
+		return false
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(datadir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// loadData reads the files of the data dir into Site.Data, keyed by their
+// path relative to the data dir (without the extension), eg
+// data/team/authors.yaml is at Data["team"]["authors"]. Files with the same
+// key, eg data/team.yaml and data/team/authors.yaml, are build errors.
+func (s *Site) loadData(ctx *BuildContext) {
+	s.Data = map[string]any{}
+	if s.DataDir == "" {
+		return
+	}
+	if _, err := os.Stat(s.DataDir); os.IsNotExist(err) {
+		return
+	}
+
+	count := 0
+	files := map[string]string{} // data file of each key
+	err := filepath.WalkDir(s.DataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.DataDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		decode := dataDecoders[ext]
+		if decode == nil || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			ctx.AddError(fmt.Errorf("data file %s: %w", path, err))
+			return nil
+		}
+		val, err := decode(contents)
+		if err != nil {
+			ctx.AddError(fmt.Errorf("data file %s: %w", path, err))
+			return nil
+		}
+
+		rel, _ := filepath.Rel(s.DataDir, strings.TrimSuffix(path, filepath.Ext(path)))
+		key := filepath.ToSlash(rel)
+		for other, file := range files {
+			if other == key || strings.HasPrefix(other, key+"/") || strings.HasPrefix(key, other+"/") {
+				ctx.AddError(fmt.Errorf("data file %s conflicts with %s as both set the same data", path, file))
+				return nil
+			}
+		}
+		files[key] = path
+
+		keys := strings.Split(key, "/")
+		node := s.Data
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := node[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[key] = child
+			}
+			node = child
+		}
+		node[keys[len(keys)-1]] = normalizeData(val)
+		count++
+		return nil
+	})
+	if err != nil {
+		ctx.AddError(fmt.Errorf("data dir %s: %w", s.DataDir, err))
+	}
+	log.Printf("[Data] Loaded %d data files from %s", count, s.DataDir)
+}
+
+// DataValue returns the value at a dotted path in Site.Data, eg
+// "authors.alice.twitter" for the twitter field of alice in the authors
+// data file. Lists are indexed by number, eg "team.0.name". Returns nil if
+// the path does not exist, or all the data for an empty path.
+func (s *Site) DataValue(path string) any {
+	var val any = s.Data
+	if path == "" {
+		return val
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := val.(type) {
+		case map[string]any:
+			val = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			val = v[i]
+		default:
+			return nil
+		}
+	}
+	return val
+}
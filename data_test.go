@@ -0,0 +1,73 @@
+package s3gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataValue(t *testing.T) {
+	site := &Site{Data: map[string]any{
+		"authors": map[string]any{
+			"alice": map[string]any{"twitter": "@alice"},
+		},
+		"team": []any{
+			map[string]any{"name": "Bob"},
+			"Carol",
+		},
+		"title": "Site",
+	}}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"authors.alice.twitter", "@alice"},
+		{"team.0.name", "Bob"},
+		{"team.1", "Carol"},
+		{"title", "Site"},
+		{"authors.bob.twitter", nil},
+		{"authors.alice.twitter.more", nil},
+		{"team.2", nil},
+		{"team.-1", nil},
+		{"team.first", nil},
+		{"title.length", nil},
+		{"missing", nil},
+	}
+	for _, test := range tests {
+		if got := site.DataValue(test.path); got != test.want {
+			t.Errorf("DataValue(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+	if got, ok := site.DataValue("").(map[string]any); !ok || len(got) != 3 {
+		t.Errorf("DataValue(\"\") = %v, want all the data", got)
+	}
+}
+
+func TestIsDataPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := &Site{DataDir: "./data"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(wd, "data", "team.yaml"), true},
+		{filepath.Join(wd, "data", "team", "authors.json"), true},
+		{filepath.Join("data", "team.yaml"), true},
+		{filepath.Join(wd, "content", "team.md"), false},
+		{filepath.Join(wd, "database.yaml"), false},
+		{filepath.Join(wd, "data2", "team.yaml"), false},
+	}
+	for _, test := range tests {
+		if got := site.isDataPath(test.path); got != test.want {
+			t.Errorf("isDataPath(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+	if (&Site{}).isDataPath(filepath.Join(wd, "data", "team.yaml")) {
+		t.Errorf("isDataPath() = true without a data dir")
+	}
+}
@@ -0,0 +1,132 @@
+package s3gen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dataItems is the items front matter of a parametric page that generates a
+// page for each record of a data file (see Site.DataDir):
+//
+//	items:
+//	  source: products   # path of the records in Site.Data, as for Data
+//	  key: sku           # field of each record naming its page (default: id)
+//
+// "items: products" is a shorthand for the default key. The records are a
+// list, or a map whose keys name the pages of records without the key field.
+// Pages with several params, eg [year]/[slug].md, take their params from the
+// fields of each record with the same names instead of the key.
+type dataItems struct {
+	Source string
+	Key    string
+}
+
+// dataItemsOf returns the items of a parametric page and false if its pages
+// are not generated from data.
+func dataItemsOf(res *Resource) (d dataItems, ok bool) {
+	if !res.IsParametric {
+		return
+	}
+	val, found := res.FrontMatter().Data["items"]
+	if !found || val == nil {
+		return
+	}
+
+	d = dataItems{Key: "id"}
+	if source, isString := val.(string); isString {
+		d.Source = source
+	} else {
+		if v, ok := frontMatterValue(val, "source"); ok {
+			d.Source = fmt.Sprint(v)
+		}
+		if v, ok := frontMatterValue(val, "key"); ok {
+			d.Key = fmt.Sprint(v)
+		}
+	}
+	return d, d.Source != ""
+}
+
+// dataRecord is a record of a data file and the value naming its page.
+type dataRecord struct {
+	Key  string
+	Item any
+}
+
+// records returns the records of the items' source with their keys (empty
+// for records of a list without the key field).
+func (d dataItems) records(s *Site) (out []dataRecord, err error) {
+	recordKey := func(item any, fallback string) string {
+		if v, ok := frontMatterValue(item, d.Key); ok {
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+		return fallback
+	}
+
+	switch val := s.DataValue(d.Source).(type) {
+	case []any:
+		for _, item := range val {
+			out = append(out, dataRecord{recordKey(item, ""), item})
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			out = append(out, dataRecord{recordKey(val[k], k), val[k]})
+		}
+	case nil:
+		return nil, fmt.Errorf("data %s not found", d.Source)
+	default:
+		return nil, fmt.Errorf("data %s is not a list or map of records", d.Source)
+	}
+	return
+}
+
+// dataTargets returns a target for each record of a parametric page's items,
+// with the record as the target's Item. The param of a page with a single
+// param is the record's key, and the params of a page with several params,
+// eg [year]/[slug].md, are the record's fields of the same names.
+func (p *ParametricPages) dataTargets(s *Site, r *Resource, items dataItems) (targets []*Resource) {
+	records, err := items.records(s)
+	if err != nil {
+		s.addResourceError(r, err)
+		return nil
+	}
+
+	names := paramNames(r.RelPath())
+	seen := map[string]int{}
+	for i, record := range records {
+		params := map[string]string{}
+		if len(names) == 1 {
+			if record.Key == "" {
+				s.addResourceError(r, fmt.Errorf("record %d of %s has no %s field", i, items.Source, items.Key))
+				continue
+			}
+			params[names[0]] = record.Key
+		} else {
+			for _, name := range names {
+				if v, ok := frontMatterValue(record.Item, name); ok {
+					params[name] = strings.TrimSpace(fmt.Sprint(v))
+				}
+			}
+		}
+
+		destpath, err := paramTargetPath(s, r, params)
+		if err != nil {
+			s.addResourceError(r, fmt.Errorf("record %d of %s: %w", i, items.Source, err))
+			continue
+		}
+		if other, ok := seen[destpath]; ok {
+			s.addResourceError(r, fmt.Errorf("records %d and %d of %s have the same page", other, i, items.Source))
+			continue
+		}
+		seen[destpath] = i
+		destres := p.paramTarget(s, r, destpath, params)
+		destres.Item = record.Item
+		targets = append(targets, destres)
+	}
+	return
+}
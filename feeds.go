@@ -0,0 +1,906 @@
+package s3gen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"maps"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// FeedFormat is an output format of a FeedGenerator.
+type FeedFormat string
+
+const (
+	// FeedRSS is an RSS 2.0 feed, written to OutputPath.
+	FeedRSS FeedFormat = "rss"
+
+	// FeedAtom is an Atom 1.0 feed, written to AtomPath.
+	FeedAtom FeedFormat = "atom"
+
+	// FeedJSON is a JSON Feed 1.1 feed, written to JSONPath.
+	FeedJSON FeedFormat = "json"
+)
+
+// FeedGenerator generates feeds in the Finalize phase. It collects the
+// pages of the feed once and writes them in each of the configured formats
+// (RSS 2.0, Atom and JSON Feed).
+type FeedGenerator struct {
+	// Title is the feed title
+	Title string
+
+	// Description is the feed description
+	Description string
+
+	// BaseURL is the base URL for the site (e.g., "https://example.com")
+	BaseURL string
+
+	// Formats are the formats to write (default: RSS only)
+	Formats []FeedFormat
+
+	// FeedPath is the URL path for the RSS feed, below the site's PathPrefix
+	// (default: "/feed.xml")
+	FeedPath string
+
+	// OutputPath is the file path to write the RSS feed (default: "feed.xml")
+	OutputPath string
+
+	// AtomPath is the file path to write the Atom feed (default: "atom.xml")
+	AtomPath string
+
+	// JSONPath is the file path to write the JSON feed (default: "feed.json")
+	JSONPath string
+
+	// Section is the directory (relative to ContentRoot) whose pages are in
+	// the feed, eg "blog". Pages in its subdirectories are included too.
+	// Use "." for all pages. Default: "blog" unless ContentPattern is set
+	Section string
+
+	// Tag limits the feed to pages with this tag
+	Tag string
+
+	// Filter is an optional function selecting the pages in the feed
+	Filter ResourceFilterFunc
+
+	// IncludeDrafts includes pages marked as drafts
+	IncludeDrafts bool
+
+	// ContentPattern is an optional glob pattern the generated page (relative
+	// to OutputDir) must match, eg "blog/*/index.html"
+	ContentPattern string
+
+	// MaxItems is the maximum number of items in the feed (default: 20)
+	MaxItems int
+
+	// Author is the default author of the feed's items
+	Author string
+
+	// AuthorEmail is the email address of the default author
+	AuthorEmail string
+
+	// FullContent includes the rendered HTML of each page in the feed
+	// instead of only its description
+	FullContent bool
+
+	// Podcast makes the RSS feed a podcast feed with iTunes tags
+	Podcast *Podcast
+
+	// TagFeeds writes a feed for each tag used by the pages in the feed
+	TagFeeds bool
+
+	// TagFeedPath is the directory of each tag feed, with {tag} replaced by
+	// the tag's slug (default: "tags/{tag}")
+	TagFeedPath string
+
+	// SectionFeeds writes a feed for each top level section of the site
+	SectionFeeds bool
+
+	// SectionFeedPath is the directory of each section feed, with {section}
+	// replaced by the section's name (default: "{section}")
+	SectionFeedPath string
+
+	// collected items during build
+	items []feedItem
+}
+
+// RSSGenerator generates an RSS 2.0 feed. It is a FeedGenerator with the
+// default formats and is kept so existing configurations continue to work.
+type RSSGenerator = FeedGenerator
+
+// feedItem is a page in the feed, shared by all the output formats.
+type feedItem struct {
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Author      string
+	Categories  []string
+	PubDate     time.Time
+	Updated     time.Time
+	GUID        string
+	Enclosure   *feedEnclosure
+	Episode     *podcastEpisode
+
+	// directory of the page relative to ContentRoot
+	dir string
+}
+
+// feedEnclosure is a media file attached to a feed item.
+type feedEnclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// feed is one of the feeds written by a FeedGenerator.
+type feed struct {
+	Title string
+
+	// Dir is the directory the feed files are written to, or "" for the
+	// main feed which uses the configured paths
+	Dir string
+
+	Items []feedItem
+}
+
+type rssXML struct {
+	XMLName      xml.Name      `xml:"rss"`
+	Version      string        `xml:"version,attr"`
+	ContentNS    string        `xml:"xmlns:content,attr,omitempty"`
+	DublinCoreNS string        `xml:"xmlns:dc,attr,omitempty"`
+	AtomNS       string        `xml:"xmlns:atom,attr,omitempty"`
+	ITunesNS     string        `xml:"xmlns:itunes,attr,omitempty"`
+	Channel      rssChannelXML `xml:"channel"`
+}
+
+type rssChannelXML struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	SelfLink      *atomLinkXML `xml:"atom:link,omitempty"`
+	PubDate       string       `xml:"pubDate,omitempty"`
+	LastBuildDate string       `xml:"lastBuildDate,omitempty"`
+	Language      string       `xml:"language,omitempty"`
+	Copyright     string       `xml:"copyright,omitempty"`
+	*itunesChannelXML
+	Items []rssItemXML `xml:"item"`
+}
+
+type rssItemXML struct {
+	Title       string           `xml:"title"`
+	Link        string           `xml:"link"`
+	Description string           `xml:"description,omitempty"`
+	Content     *cdataXML        `xml:"content:encoded,omitempty"`
+	Creator     string           `xml:"dc:creator,omitempty"`
+	Categories  []string         `xml:"category,omitempty"`
+	Enclosure   *rssEnclosureXML `xml:"enclosure,omitempty"`
+	PubDate     string           `xml:"pubDate,omitempty"`
+	GUID        *rssGUIDXML      `xml:"guid,omitempty"`
+	*itunesItemXML
+}
+
+type rssEnclosureXML struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUIDXML struct {
+	IsPermaLink string `xml:"isPermaLink,attr,omitempty"`
+	Value       string `xml:",chardata"`
+}
+
+type cdataXML struct {
+	Value string `xml:",cdata"`
+}
+
+type atomXML struct {
+	XMLName xml.Name       `xml:"feed"`
+	NS      string         `xml:"xmlns,attr"`
+	Base    string         `xml:"xml:base,attr,omitempty"`
+	Title   string         `xml:"title"`
+	Sub     string         `xml:"subtitle,omitempty"`
+	ID      string         `xml:"id"`
+	Updated string         `xml:"updated"`
+	Links   []atomLinkXML  `xml:"link"`
+	Author  *atomAuthorXML `xml:"author,omitempty"`
+	Entries []atomEntryXML `xml:"entry"`
+}
+
+type atomLinkXML struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomAuthorXML struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomCategoryXML struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomTextXML struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntryXML struct {
+	Base       string            `xml:"xml:base,attr,omitempty"`
+	Title      string            `xml:"title"`
+	ID         string            `xml:"id"`
+	Links      []atomLinkXML     `xml:"link"`
+	Published  string            `xml:"published,omitempty"`
+	Updated    string            `xml:"updated"`
+	Author     *atomAuthorXML    `xml:"author,omitempty"`
+	Categories []atomCategoryXML `xml:"category,omitempty"`
+	Summary    *atomTextXML      `xml:"summary,omitempty"`
+	Content    *atomTextXML      `xml:"content,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonFeedFile   `json:"attachments,omitempty"`
+}
+
+type jsonFeedFile struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size_in_bytes,omitempty"`
+}
+
+// Phase returns PhaseFinalize - feed generation happens after all content is generated.
+func (g *FeedGenerator) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns patterns for HTML files.
+func (g *FeedGenerator) DependsOn() []string {
+	return []string{"**/*.html"}
+}
+
+// Produces returns the paths of the main feeds written.
+func (g *FeedGenerator) Produces() (out []string) {
+	for _, format := range g.formats() {
+		out = append(out, g.outputPath(format))
+	}
+	return
+}
+
+// TargetsFor returns nil - FeedGenerator uses hooks instead of per-resource targets.
+func (g *FeedGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (g *FeedGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the feed generator to a site.
+func (g *FeedGenerator) Register(site *Site) {
+	// Set defaults
+	if g.OutputPath == "" {
+		g.OutputPath = "feed.xml"
+	}
+	if g.FeedPath == "" {
+		g.FeedPath = "/feed.xml"
+	}
+	if g.AtomPath == "" {
+		g.AtomPath = "atom.xml"
+	}
+	if g.JSONPath == "" {
+		g.JSONPath = "feed.json"
+	}
+	if g.TagFeedPath == "" {
+		g.TagFeedPath = "tags/{tag}"
+	}
+	if g.SectionFeedPath == "" {
+		g.SectionFeedPath = "{section}"
+	}
+	if g.MaxItems == 0 {
+		g.MaxItems = 20
+	}
+	if g.Section == "" && g.ContentPattern == "" {
+		// Feeds have always been of the blog by default
+		g.Section = "blog"
+	}
+
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	// Reset items at start of build
+	site.Hooks.OnPhaseStart(PhaseDiscover, func(ctx *BuildContext) {
+		g.items = nil
+	})
+
+	// Collect items as resources are processed
+	site.Hooks.OnResourceProcessed(func(ctx *BuildContext, res *Resource, targets []*Resource) {
+		for _, target := range targets {
+			if item, ok := g.collectItem(ctx.Site, res, target); ok {
+				g.items = append(g.items, item)
+			}
+		}
+	})
+
+	// Write feeds at end of Finalize phase
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		for _, f := range g.feeds() {
+			for _, format := range g.formats() {
+				if err := g.writeFeed(ctx.Site, format, f); err != nil {
+					ctx.AddError(fmt.Errorf("%s feed generation failed: %w", format, err))
+				}
+			}
+		}
+	})
+}
+
+func (g *FeedGenerator) formats() []FeedFormat {
+	if len(g.Formats) == 0 {
+		return []FeedFormat{FeedRSS}
+	}
+	return g.Formats
+}
+
+func (g *FeedGenerator) outputPath(format FeedFormat) string {
+	switch format {
+	case FeedAtom:
+		return g.AtomPath
+	case FeedJSON:
+		return g.JSONPath
+	}
+	return g.OutputPath
+}
+
+// feedPath returns the path (relative to OutputDir) a feed is written to.
+func (g *FeedGenerator) feedPath(f feed, format FeedFormat) string {
+	if f.Dir == "" {
+		return g.outputPath(format)
+	}
+	return filepath.Join(filepath.FromSlash(f.Dir), filepath.Base(g.outputPath(format)))
+}
+
+// feedURL returns the absolute URL a feed is served at.
+func (g *FeedGenerator) feedURL(site *Site, f feed, format FeedFormat) string {
+	if f.Dir == "" && format == FeedRSS {
+		return g.absURL(site.PathRelUrl(g.FeedPath))
+	}
+	return g.absURL(site.PathRelUrl("/" + filepath.ToSlash(g.feedPath(f, format))))
+}
+
+// absURL returns the absolute URL of a path on the site, which includes the
+// site's PathPrefix.
+func (g *FeedGenerator) absURL(path string) string {
+	return strings.TrimSuffix(g.BaseURL, "/") + path
+}
+
+// collectItem returns the feed item for a page generated from res, if the
+// page can be in one of the feeds. Section and Tag are applied when the
+// feeds are written so the per-section and per-tag feeds can share the
+// collected items.
+func (g *FeedGenerator) collectItem(site *Site, res *Resource, target *Resource) (item feedItem, ok bool) {
+	if res == nil || !strings.HasSuffix(target.FullPath, ".html") || isLaterPage(target) {
+		return
+	}
+
+	// Get relative path from output dir
+	relPath, err := filepath.Rel(site.OutputDir, target.FullPath)
+	if err != nil {
+		return
+	}
+	relPath = filepath.ToSlash(relPath)
+	if g.ContentPattern != "" {
+		if matched, _ := filepath.Match(g.ContentPattern, relPath); !matched {
+			return
+		}
+	}
+
+	// Skip section index pages (listing pages) - these are _index files and
+	// index files at the top of a section
+	contentPath, err := filepath.Rel(site.ContentRoot, res.FullPath)
+	if err != nil || strings.HasPrefix(contentPath, "..") {
+		return
+	}
+	item.dir = filepath.ToSlash(filepath.Dir(contentPath))
+	name := filepath.Base(contentPath)
+	if strings.HasPrefix(name, "_index.") || (isIndexPath(name) && !strings.Contains(item.dir, "/")) {
+		return
+	}
+	if isParametricPath(res.FullPath) {
+		return
+	}
+
+	// Get metadata from source resource
+	fm := res.FrontMatter().Data
+	if fm == nil {
+		return
+	}
+	if draft, _ := fm["draft"].(bool); draft && !g.IncludeDrafts {
+		return
+	}
+	if g.Filter != nil && !g.Filter(res) {
+		return
+	}
+
+	if t, ok := fm["title"].(string); ok {
+		item.Title = t
+	}
+	if item.Title == "" {
+		return // Skip items without title
+	}
+	if d, ok := fm["description"].(string); ok {
+		item.Description = d
+	} else if d, ok := fm["summary"].(string); ok {
+		item.Description = d
+	}
+	if a, ok := fm["author"].(string); ok {
+		item.Author = a
+	}
+	if tags, ok := fm["tags"].([]any); ok {
+		for _, tag := range tags {
+			if t, ok := tag.(string); ok {
+				item.Categories = append(item.Categories, t)
+			}
+		}
+	}
+	if d, ok := fm["date"]; ok {
+		if item.PubDate, ok = parseDate(d); !ok {
+			log.Printf("[Feed] Cannot parse date %v in %s", d, res.FullPath)
+		}
+	}
+	if d, ok := fm["lastmod"]; ok {
+		item.Updated, _ = parseDate(d)
+	}
+
+	if g.FullContent {
+		item.Content = string(res.RenderedContent())
+	}
+	if item.Author == "" {
+		item.Author = g.Author
+	}
+
+	urlPath := site.TargetURL(target)
+	item.Link = urlPath
+	item.GUID = urlPath
+
+	if enclosure, ok := fm["enclosure"]; ok {
+		item.Enclosure = g.enclosure(site, res, urlPath, enclosure)
+	}
+	if g.Podcast != nil && !g.episode(site, res, &item, fm) {
+		return // Only episodes are in podcast feeds
+	}
+	return item, true
+}
+
+// enclosure returns the media file attached to a page from its enclosure
+// front matter. This is either the URL of the file or a map with url, type
+// and length fields. Files co-located with the page are found in its assets
+// and their length is taken from the file.
+func (g *FeedGenerator) enclosure(site *Site, res *Resource, pageURL string, value any) *feedEnclosure {
+	enc := &feedEnclosure{}
+	if u, ok := value.(string); ok {
+		enc.URL = u
+	} else {
+		if u, ok := frontMatterValue(value, "url"); ok {
+			enc.URL = fmt.Sprint(u)
+		}
+		if t, ok := frontMatterValue(value, "type"); ok {
+			enc.Type = fmt.Sprint(t)
+		}
+		if l, ok := frontMatterValue(value, "length"); ok {
+			enc.Length = int64(gotl.ToInt(l))
+		}
+	}
+	if enc.URL == "" {
+		return nil
+	}
+
+	var asset *Resource
+	file := siteFile(site, enc.URL)
+	enc.URL, asset = g.mediaURL(site, res, pageURL, enc.URL)
+	if asset != nil {
+		file = asset.FullPath
+	}
+	if file != "" && enc.Length == 0 {
+		if info, err := os.Stat(file); err == nil {
+			enc.Length = info.Size()
+		}
+	}
+	if enc.Type == "" {
+		enc.Type = mediaType(enc.URL)
+	}
+	return enc
+}
+
+// mediaURL returns the absolute URL of a file referred to from the front
+// matter of the page at pageURL, along with the co-located asset it refers
+// to if any.
+func (g *FeedGenerator) mediaURL(site *Site, res *Resource, pageURL string, link string) (string, *Resource) {
+	asset := findAsset(res, link)
+	if asset != nil {
+		link = GetAssetURL(site, res, filepath.Base(asset.FullPath))
+	}
+	if u, err := url.Parse(link); err == nil && u.Scheme == "" {
+		base, _ := url.Parse(pageURL)
+		link = g.absURL(base.ResolveReference(u).String())
+	}
+	return link, asset
+}
+
+// siteFile returns the file an absolute site path, eg /media/ep1.mp3, refers
+// to in the ContentRoot or OutputDir, or "" if there is none.
+func siteFile(site *Site, link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return ""
+	}
+	p, _ := cutPathPrefix(u.Path, site.PathPrefix)
+	for _, root := range []string{site.ContentRoot, site.OutputDir} {
+		fullpath := filepath.Join(root, filepath.FromSlash(p))
+		if info, err := os.Stat(fullpath); err == nil && !info.IsDir() {
+			return fullpath
+		}
+	}
+	return ""
+}
+
+// mediaTypes are the types of media files commonly attached to feed items
+// which are not in Go's builtin table.
+var mediaTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/x-m4a",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+}
+
+// mediaType returns the MIME type of a media file from its extension.
+func mediaType(link string) string {
+	if u, err := url.Parse(link); err == nil {
+		link = u.Path
+	}
+	ext := strings.ToLower(path.Ext(link))
+	if t, ok := mediaTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// feeds returns the feeds to write: the main feed along with the tag and
+// section feeds if enabled.
+func (g *FeedGenerator) feeds() (out []feed) {
+	var items []feedItem
+	for _, item := range g.items {
+		if g.Section != "" && g.Section != "." && item.dir != g.Section && !strings.HasPrefix(item.dir, g.Section+"/") {
+			continue
+		}
+		if g.Tag != "" && !item.hasTag(g.Tag) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sortFeedItems(items)
+	if len(items) == 0 {
+		return
+	}
+	out = append(out, feed{Title: g.Title, Items: g.limit(items)})
+
+	if g.TagFeeds {
+		tags := map[string][]feedItem{}
+		names := map[string]string{}
+		for _, item := range items {
+			for _, tag := range item.Categories {
+				slug := gotl.Slugify(tag)
+				if _, ok := names[slug]; !ok {
+					names[slug] = tag
+				}
+				tags[slug] = append(tags[slug], item)
+			}
+		}
+		for _, slug := range slices.Sorted(maps.Keys(tags)) {
+			out = append(out, feed{
+				Title: fmt.Sprintf("%s - %s", g.Title, names[slug]),
+				Dir:   strings.ReplaceAll(g.TagFeedPath, "{tag}", slug),
+				Items: g.limit(tags[slug]),
+			})
+		}
+	}
+
+	if g.SectionFeeds {
+		sections := map[string][]feedItem{}
+		for _, item := range items {
+			if section, _, _ := strings.Cut(item.dir, "/"); section != "." {
+				sections[section] = append(sections[section], item)
+			}
+		}
+		for _, section := range slices.Sorted(maps.Keys(sections)) {
+			out = append(out, feed{
+				Title: fmt.Sprintf("%s - %s", g.Title, section),
+				Dir:   strings.ReplaceAll(g.SectionFeedPath, "{section}", section),
+				Items: g.limit(sections[section]),
+			})
+		}
+	}
+	return
+}
+
+func (item *feedItem) hasTag(tag string) bool {
+	tag = gotl.Slugify(tag)
+	for _, t := range item.Categories {
+		if gotl.Slugify(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFeedItems sorts items newest first.
+func sortFeedItems(items []feedItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].PubDate.Equal(items[j].PubDate) {
+			return items[i].PubDate.After(items[j].PubDate)
+		}
+		return items[i].Link < items[j].Link
+	})
+}
+
+// limit returns at most MaxItems items.
+func (g *FeedGenerator) limit(items []feedItem) []feedItem {
+	if len(items) > g.MaxItems {
+		return items[:g.MaxItems]
+	}
+	return items
+}
+
+// lastUpdated returns the time the most recently changed item was updated.
+func lastUpdated(items []feedItem) (out time.Time) {
+	for _, item := range items {
+		if item.Updated.After(out) {
+			out = item.Updated
+		}
+		if item.PubDate.After(out) {
+			out = item.PubDate
+		}
+	}
+	return
+}
+
+func (g *FeedGenerator) writeFeed(site *Site, format FeedFormat, f feed) error {
+	var out any
+	switch format {
+	case FeedRSS:
+		out = g.rssFeed(site, f)
+	case FeedAtom:
+		out = g.atomFeed(site, f)
+	case FeedJSON:
+		out = g.jsonFeed(site, f)
+	default:
+		return fmt.Errorf("unknown feed format %q", format)
+	}
+
+	outPath := filepath.Join(site.OutputDir, g.feedPath(f, format))
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	log.Printf("[Feed] Writing %d items to %s", len(f.Items), outPath)
+	if format == FeedJSON {
+		return writeJSON(outPath, out)
+	}
+	return writeXML(outPath, out)
+}
+
+func (g *FeedGenerator) rssFeed(site *Site, f feed) rssXML {
+	items := f.Items
+	out := rssXML{
+		Version:      "2.0",
+		ContentNS:    "http://purl.org/rss/1.0/modules/content/",
+		DublinCoreNS: "http://purl.org/dc/elements/1.1/",
+		AtomNS:       "http://www.w3.org/2005/Atom",
+		Channel: rssChannelXML{
+			Title:       f.Title,
+			Link:        g.absURL(site.PathRelUrl("/")),
+			Description: g.Description,
+			SelfLink:    &atomLinkXML{Href: g.feedURL(site, f, FeedRSS), Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	if len(items) > 0 && !items[0].PubDate.IsZero() {
+		out.Channel.PubDate = items[0].PubDate.Format(time.RFC1123Z)
+	}
+	if updated := lastUpdated(items); !updated.IsZero() {
+		out.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+	if g.Podcast != nil {
+		out.ITunesNS = itunesNS
+		out.Channel.Language = g.Podcast.Language
+		if out.Channel.Language == "" {
+			out.Channel.Language = "en"
+		}
+		out.Channel.Copyright = g.Podcast.Copyright
+		out.Channel.itunesChannelXML = g.itunesChannel()
+	}
+
+	for _, item := range items {
+		fullURL := g.absURL(item.Link)
+		entry := rssItemXML{
+			Title:       item.Title,
+			Link:        fullURL,
+			Description: item.Description,
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			GUID:        &rssGUIDXML{Value: g.absURL(item.GUID)},
+		}
+		if item.Content != "" {
+			entry.Content = &cdataXML{Value: item.Content}
+		}
+		if enc := item.Enclosure; enc != nil {
+			entry.Enclosure = &rssEnclosureXML{URL: enc.URL, Length: enc.Length, Type: enc.Type}
+		}
+		if !item.PubDate.IsZero() {
+			entry.PubDate = item.PubDate.Format(time.RFC1123Z)
+		}
+		if item.Episode != nil {
+			entry.itunesItemXML = itunesItem(item)
+		}
+		out.Channel.Items = append(out.Channel.Items, entry)
+	}
+	return out
+}
+
+func (g *FeedGenerator) atomFeed(site *Site, f feed) atomXML {
+	items := f.Items
+	homeURL := g.absURL(site.PathRelUrl("/"))
+	out := atomXML{
+		NS:    "http://www.w3.org/2005/Atom",
+		Base:  homeURL,
+		Title: f.Title,
+		Sub:   g.Description,
+		ID:    g.feedURL(site, f, FeedAtom),
+		Links: []atomLinkXML{
+			{Href: homeURL},
+			{Href: g.feedURL(site, f, FeedAtom), Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	// Atom requires an author on the feed or on every entry
+	if g.Author != "" {
+		out.Author = &atomAuthorXML{Name: g.Author, Email: g.AuthorEmail}
+	} else {
+		out.Author = &atomAuthorXML{Name: g.Title}
+	}
+
+	updated := lastUpdated(items)
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	out.Updated = updated.Format(time.RFC3339)
+
+	for _, item := range items {
+		fullURL := g.absURL(item.Link)
+		entry := atomEntryXML{
+			Base:  fullURL,
+			Title: item.Title,
+			ID:    g.absURL(item.GUID),
+			Links: []atomLinkXML{{Href: fullURL, Rel: "alternate", Type: "text/html"}},
+		}
+		// Atom requires an updated timestamp on every entry
+		entryUpdated := item.Updated
+		if entryUpdated.IsZero() {
+			entryUpdated = item.PubDate
+		}
+		if entryUpdated.IsZero() {
+			entryUpdated = updated
+		}
+		entry.Updated = entryUpdated.Format(time.RFC3339)
+		if !item.PubDate.IsZero() {
+			entry.Published = item.PubDate.Format(time.RFC3339)
+		}
+		if item.Author != "" && item.Author != g.Author {
+			entry.Author = &atomAuthorXML{Name: item.Author}
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategoryXML{Term: category})
+		}
+		if item.Description != "" {
+			entry.Summary = &atomTextXML{Value: item.Description}
+		}
+		if item.Content != "" {
+			entry.Content = &atomTextXML{Type: "html", Value: item.Content}
+		}
+		if enc := item.Enclosure; enc != nil {
+			entry.Links = append(entry.Links, atomLinkXML{Href: enc.URL, Rel: "enclosure", Type: enc.Type, Length: enc.Length})
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out
+}
+
+func (g *FeedGenerator) jsonFeed(site *Site, f feed) jsonFeed {
+	out := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: g.absURL(site.PathRelUrl("/")),
+		FeedURL:     g.feedURL(site, f, FeedJSON),
+		Description: g.Description,
+		Items:       []jsonFeedItem{},
+	}
+	if g.Author != "" {
+		out.Authors = []jsonFeedAuthor{{Name: g.Author}}
+	}
+
+	for _, item := range f.Items {
+		entry := jsonFeedItem{
+			ID:          g.absURL(item.GUID),
+			URL:         g.absURL(item.Link),
+			Title:       item.Title,
+			ContentHTML: item.Content,
+			Summary:     item.Description,
+			Tags:        item.Categories,
+		}
+		// JSON Feed items need content, so fall back to the description
+		if entry.ContentHTML == "" {
+			entry.ContentHTML = item.Description
+		}
+		if !item.PubDate.IsZero() {
+			entry.DatePublished = item.PubDate.Format(time.RFC3339)
+		}
+		if !item.Updated.IsZero() {
+			entry.DateModified = item.Updated.Format(time.RFC3339)
+		}
+		if item.Author != "" && item.Author != g.Author {
+			entry.Authors = []jsonFeedAuthor{{Name: item.Author}}
+		}
+		if enc := item.Enclosure; enc != nil {
+			entry.Attachments = []jsonFeedFile{{URL: enc.URL, MimeType: enc.Type, Size: enc.Length}}
+		}
+		out.Items = append(out.Items, entry)
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package s3gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSiteFile(t *testing.T) {
+	dir := t.TempDir()
+	site := &Site{
+		ContentRoot: filepath.Join(dir, "content"),
+		OutputDir:   filepath.Join(dir, "out"),
+		PathPrefix:  "/pp",
+	}
+	for _, path := range []string{"content/media/ep1.mp3", "out/static/ep2.mp3"} {
+		fullpath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullpath, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		link, want string
+	}{
+		{"/pp/media/ep1.mp3", "content/media/ep1.mp3"},
+		{"/media/ep1.mp3", "content/media/ep1.mp3"},
+		{"/pp/static/ep2.mp3", "out/static/ep2.mp3"},
+		{"/pp/media/ep1.mp3?v=2", "content/media/ep1.mp3"},
+		{"/pp/media", ""},
+		{"/pp/media/missing.mp3", ""},
+		{"media/ep1.mp3", ""},
+		{"https://example.com/media/ep1.mp3", ""},
+	}
+	for _, test := range tests {
+		want := test.want
+		if want != "" {
+			want = filepath.Join(dir, filepath.FromSlash(want))
+		}
+		if got := siteFile(site, test.link); got != want {
+			t.Errorf("siteFile(%q) = %q, want %q", test.link, got, want)
+		}
+	}
+}
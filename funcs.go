@@ -17,12 +17,31 @@ import (
 // DefaultFuncMap returns a map of the default template functions available in s3gen.
 func (s *Site) DefaultFuncMap() map[string]any {
 	return map[string]any{
-		"LeafPages":     s.LeafPages,
-		"PagesByDate":   s.GetPagesByDate,
-		"PagesByTag":    s.GetPagesByTag,
-		"AllTags":       GetAllTags,
-		"KeysForTagMap": s.KeysForTagMap,
-		"json":          s.Json,
+		"LeafPages":        s.LeafPages,
+		"PagesByDate":      s.GetPagesByDate,
+		"PagesByTag":       s.GetPagesByTag,
+		"AllTags":          GetAllTags,
+		"KeysForTagMap":    s.KeysForTagMap,
+		"Terms":            s.Terms,
+		"Term":             s.Term,
+		"TermsOf":          s.TermsOf,
+		"PagesByTerm":      s.PagesByTerm,
+		"PrevInSection":    s.PrevInSection,
+		"NextInSection":    s.NextInSection,
+		"SeriesOf":         s.SeriesOf,
+		"PositionInSeries": s.PositionInSeries,
+		"SectionTree":      s.SectionTree,
+		"Parent":           s.Parent,
+		"Children":         s.Children,
+		"Ancestors":        s.Ancestors,
+		"Menu":             s.Menu,
+		"Related":          s.Related,
+		"Data":             s.DataValue,
+		"json":             s.Json,
+		"Asset":            s.Asset,
+		"SRI":              s.SRI,
+		"ImageInfoOf":      ImageInfoOf,
+		"ImagesByDate":     ImagesByDate,
 		"debug": func(vals ...any) string {
 			log.Println(vals...)
 			return ""
@@ -47,6 +66,37 @@ func (s *Site) DefaultFuncMap() map[string]any {
 	}
 }
 
+// resourceFuncs returns the template functions that are bound to a specific
+// resource, eg for looking up its co-located assets. These are available both
+// in page templates and in the content of the resource itself.
+func resourceFuncs(res *Resource) map[string]any {
+	return map[string]any{
+		// AssetURL returns the URL for a co-located asset file.
+		// For normal pages, returns relative path (./filename).
+		// For parametric pages, returns shared assets path (/_assets/hash/filename).
+		"AssetURL": func(filename string) string {
+			return GetAssetURL(res.Site, res, filename)
+		},
+		// ImageSet returns the responsive variants of a co-located image.
+		"ImageSet": func(filename string) (*ImageSet, error) {
+			return GetImageSet(res.Site, res, filename)
+		},
+		// Ref returns the permalink of another page by its path, slug or title.
+		"Ref": func(ref string) string {
+			return res.Site.Ref(res, ref)
+		},
+		// Backlinks and OutgoingLinks return the pages linking to and linked
+		// from a page.
+		"Backlinks":     res.Site.linkGraphFunc(res, res.Site.Backlinks),
+		"OutgoingLinks": res.Site.linkGraphFunc(res, res.Site.OutgoingLinks),
+		// ImageInfo returns the dimensions, EXIF data and dominant colour of a
+		// co-located image, or nil if there is no such image.
+		"ImageInfo": func(filename string) *ImageInfo {
+			return GetImageInfo(res, filename)
+		},
+	}
+}
+
 // LeafPages returns a list of "leaf" pages (i.e., pages that are not index pages).
 // It can be filtered by draft status and sorted by date or title.
 func (s *Site) LeafPages(hideDrafts bool, orderby string, offset, count any) (out []*Resource) {
@@ -3,14 +3,29 @@ package s3gen
 import (
 	"encoding/xml"
 	"fmt"
+	"log"
+	"maps"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 )
 
 // SitemapGenerator generates a sitemap.xml in the Finalize phase.
 // It collects all generated HTML pages and outputs them as a sitemap.
+// Pages can override their entry with these front matter fields:
+//
+//	sitemap:
+//	  priority: 0.8
+//	  changefreq: daily
+//	  exclude: true
+//	alternates:        # translations of the page, by language
+//	  fr: /fr/about/
+//	lang: en           # language of the page itself
 type SitemapGenerator struct {
 	// BaseURL is the base URL for the site (e.g., "https://example.com")
 	BaseURL string
@@ -27,6 +42,14 @@ type SitemapGenerator struct {
 	// ExcludePatterns are glob patterns for paths to exclude from the sitemap
 	ExcludePatterns []string
 
+	// MaxURLs is the maximum number of URLs in a sitemap file (default:
+	// 50000, the limit of the sitemap protocol). Larger sites are split into
+	// several files listed in a sitemap index written to OutputPath.
+	MaxURLs int
+
+	// SkipImages leaves out the image:image entries for co-located images
+	SkipImages bool
+
 	// collected URLs during build
 	urls []sitemapURL
 }
@@ -36,19 +59,46 @@ type sitemapURL struct {
 	LastMod    time.Time
 	ChangeFreq string
 	Priority   float64
+	Alternates []sitemapAlternateXML
+	Images     []string
 }
 
 type sitemapXML struct {
 	XMLName xml.Name        `xml:"urlset"`
 	XMLNS   string          `xml:"xmlns,attr"`
+	XHTMLNS string          `xml:"xmlns:xhtml,attr,omitempty"`
+	ImageNS string          `xml:"xmlns:image,attr,omitempty"`
 	URLs    []sitemapURLXML `xml:"url"`
 }
 
 type sitemapURLXML struct {
-	Loc        string  `xml:"loc"`
-	LastMod    string  `xml:"lastmod,omitempty"`
-	ChangeFreq string  `xml:"changefreq,omitempty"`
-	Priority   float64 `xml:"priority,omitempty"`
+	Loc        string                `xml:"loc"`
+	LastMod    string                `xml:"lastmod,omitempty"`
+	ChangeFreq string                `xml:"changefreq,omitempty"`
+	Priority   *float64              `xml:"priority,omitempty"`
+	Alternates []sitemapAlternateXML `xml:"xhtml:link,omitempty"`
+	Images     []sitemapImageXML     `xml:"image:image,omitempty"`
+}
+
+type sitemapAlternateXML struct {
+	Rel      string `xml:"rel,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+type sitemapImageXML struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndexXML struct {
+	XMLName  xml.Name               `xml:"sitemapindex"`
+	XMLNS    string                 `xml:"xmlns,attr"`
+	Sitemaps []sitemapIndexEntryXML `xml:"sitemap"`
+}
+
+type sitemapIndexEntryXML struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
 }
 
 // Phase returns PhaseFinalize - sitemap generation happens after all content is generated.
@@ -89,6 +139,9 @@ func (g *SitemapGenerator) Register(site *Site) {
 	if g.Priority == 0 {
 		g.Priority = 0.5
 	}
+	if g.MaxURLs == 0 {
+		g.MaxURLs = 50000
+	}
 
 	// Initialize hooks if needed
 	if site.Hooks == nil {
@@ -103,7 +156,8 @@ func (g *SitemapGenerator) Register(site *Site) {
 	// Collect URLs as resources are processed
 	site.Hooks.OnResourceProcessed(func(ctx *BuildContext, res *Resource, targets []*Resource) {
 		for _, target := range targets {
-			if !strings.HasSuffix(target.FullPath, ".html") {
+			// Later pages of paginated lists are only reached from the first
+			if !strings.HasSuffix(target.FullPath, ".html") || isLaterPage(target) {
 				continue
 			}
 
@@ -118,311 +172,212 @@ func (g *SitemapGenerator) Register(site *Site) {
 				continue
 			}
 
-			// Build URL path
-			urlPath := "/" + strings.TrimSuffix(relPath, "index.html")
-			urlPath = strings.TrimSuffix(urlPath, "/") + "/"
-			if urlPath == "//" {
-				urlPath = "/"
-			}
-
-			// Get last modified time
-			var lastMod time.Time
-			if info, err := os.Stat(target.FullPath); err == nil {
-				lastMod = info.ModTime()
-			}
-
-			g.urls = append(g.urls, sitemapURL{
-				Loc:        urlPath,
-				LastMod:    lastMod,
+			u := sitemapURL{
+				Loc:        ctx.Site.TargetURL(target),
 				ChangeFreq: g.ChangeFreq,
 				Priority:   g.Priority,
-			})
+			}
+			if res != nil && !g.loadPageSettings(ctx.Site, res, &u) {
+				continue
+			}
+			g.urls = append(g.urls, u)
 		}
 	})
 
 	// Write sitemap at end of Finalize phase
 	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
-		if err := g.writeSitemap(ctx.Site.OutputDir); err != nil {
+		if err := g.writeSitemap(ctx.Site); err != nil {
 			ctx.AddError(fmt.Errorf("sitemap generation failed: %w", err))
 		}
 	})
 }
 
-func (g *SitemapGenerator) shouldExclude(path string) bool {
-	for _, pattern := range g.ExcludePatterns {
-		if matched, _ := filepath.Match(pattern, path); matched {
-			return true
+// loadPageSettings applies the front matter of the page's source to its
+// sitemap entry. Returns false if the page is excluded from the sitemap.
+func (g *SitemapGenerator) loadPageSettings(site *Site, res *Resource, u *sitemapURL) bool {
+	fm := res.FrontMatter().Data
+	if fm != nil {
+		if exclude, _ := frontMatterValue(fm, "sitemap", "exclude"); exclude == true {
+			return false
+		}
+		if v, ok := frontMatterValue(fm, "sitemap", "priority"); ok {
+			switch p := v.(type) {
+			case float64:
+				u.Priority = p
+			case int:
+				u.Priority = float64(p)
+			case int64:
+				u.Priority = float64(p)
+			}
+		}
+		if v, ok := frontMatterValue(fm, "sitemap", "changefreq"); ok {
+			u.ChangeFreq = fmt.Sprint(v)
 		}
-	}
-	return false
-}
 
-func (g *SitemapGenerator) writeSitemap(outputDir string) error {
-	if len(g.urls) == 0 {
-		return nil
-	}
+		// Last modified time comes from the content, as output files are
+		// always new
+		for _, field := range []string{"lastmod", "date"} {
+			if t, ok := parseDate(fm[field]); ok {
+				u.LastMod = t
+				break
+			}
+		}
 
-	sitemap := sitemapXML{
-		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		u.Alternates = g.alternates(site, res, u.Loc, fm)
 	}
 
-	for _, u := range g.urls {
-		fullURL := strings.TrimSuffix(g.BaseURL, "/") + u.Loc
-		entry := sitemapURLXML{
-			Loc:        fullURL,
-			ChangeFreq: u.ChangeFreq,
-			Priority:   u.Priority,
-		}
-		if !u.LastMod.IsZero() {
-			entry.LastMod = u.LastMod.Format("2006-01-02")
+	if !g.SkipImages {
+		for _, asset := range res.Assets {
+			if isImagePath(asset.FullPath) {
+				u.Images = append(u.Images, g.absURL(u.Loc, GetAssetURL(site, res, filepath.Base(asset.FullPath))))
+			}
 		}
-		sitemap.URLs = append(sitemap.URLs, entry)
-	}
-
-	outPath := filepath.Join(outputDir, g.OutputPath)
-	f, err := os.Create(outPath)
-	if err != nil {
-		return err
 	}
-	defer f.Close()
-
-	f.WriteString(xml.Header)
-	enc := xml.NewEncoder(f)
-	enc.Indent("", "  ")
-	return enc.Encode(sitemap)
-}
-
-// RSSGenerator generates an RSS feed in the Finalize phase.
-// It collects blog posts and outputs them as an RSS 2.0 feed.
-type RSSGenerator struct {
-	// Title is the feed title
-	Title string
-
-	// Description is the feed description
-	Description string
-
-	// BaseURL is the base URL for the site (e.g., "https://example.com")
-	BaseURL string
-
-	// FeedPath is the URL path for the feed (default: "/feed.xml")
-	FeedPath string
-
-	// OutputPath is the file path to write the feed (default: "feed.xml")
-	OutputPath string
-
-	// ContentPattern is a glob pattern to match content files for the feed
-	// Default: "blog/**/*.html"
-	ContentPattern string
-
-	// MaxItems is the maximum number of items in the feed (default: 20)
-	MaxItems int
-
-	// collected items during build
-	items []rssItem
-}
-
-type rssItem struct {
-	Title       string
-	Link        string
-	Description string
-	PubDate     time.Time
-	GUID        string
-}
-
-type rssXML struct {
-	XMLName xml.Name      `xml:"rss"`
-	Version string        `xml:"version,attr"`
-	Channel rssChannelXML `xml:"channel"`
-}
-
-type rssChannelXML struct {
-	Title       string       `xml:"title"`
-	Link        string       `xml:"link"`
-	Description string       `xml:"description"`
-	PubDate     string       `xml:"pubDate,omitempty"`
-	Items       []rssItemXML `xml:"item"`
-}
-
-type rssItemXML struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description,omitempty"`
-	PubDate     string `xml:"pubDate,omitempty"`
-	GUID        string `xml:"guid,omitempty"`
-}
-
-// Phase returns PhaseFinalize - RSS generation happens after all content is generated.
-func (g *RSSGenerator) Phase() BuildPhase {
-	return PhaseFinalize
+	return true
 }
 
-// DependsOn returns patterns for HTML files.
-func (g *RSSGenerator) DependsOn() []string {
-	return []string{"**/*.html"}
-}
-
-// Produces returns the feed.xml pattern.
-func (g *RSSGenerator) Produces() []string {
-	return []string{"feed.xml"}
-}
-
-// TargetsFor returns nil - RSSGenerator uses hooks instead of per-resource targets.
-func (g *RSSGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
-	return nil, nil
-}
-
-// Run is a no-op - actual work is done via hooks.
-func (g *RSSGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
-	return nil
-}
-
-// Register adds the RSS generator to a site.
-func (g *RSSGenerator) Register(site *Site) {
-	// Set defaults
-	if g.OutputPath == "" {
-		g.OutputPath = "feed.xml"
-	}
-	if g.FeedPath == "" {
-		g.FeedPath = "/feed.xml"
-	}
-	if g.ContentPattern == "" {
-		g.ContentPattern = "blog/**/*.html"
+// alternates returns the hreflang links of a page from its alternates front
+// matter, which maps languages to the URLs (or references, see
+// Site.ResolveRef) of the translations of the page. The page itself is
+// included if its language is set with the lang field.
+func (g *SitemapGenerator) alternates(site *Site, res *Resource, loc string, fm map[string]any) (out []sitemapAlternateXML) {
+	langs := map[string]string{}
+	switch alternates := fm["alternates"].(type) {
+	case map[string]any:
+		for lang, link := range alternates {
+			langs[lang] = fmt.Sprint(link)
+		}
+	case map[any]any:
+		for lang, link := range alternates {
+			langs[fmt.Sprint(lang)] = fmt.Sprint(link)
+		}
 	}
-	if g.MaxItems == 0 {
-		g.MaxItems = 20
+	if len(langs) == 0 {
+		return nil
 	}
-
-	// Initialize hooks if needed
-	if site.Hooks == nil {
-		site.Hooks = NewHookRegistry()
+	if lang, ok := fm["lang"].(string); ok && lang != "" {
+		langs[lang] = loc
 	}
 
-	// Reset items at start of build
-	site.Hooks.OnPhaseStart(PhaseDiscover, func(ctx *BuildContext) {
-		g.items = nil
-	})
-
-	// Collect items as resources are processed
-	site.Hooks.OnResourceProcessed(func(ctx *BuildContext, res *Resource, targets []*Resource) {
-		for _, target := range targets {
-			if !strings.HasSuffix(target.FullPath, ".html") {
-				continue
-			}
-
-			// Get relative path from output dir
-			relPath, err := filepath.Rel(ctx.Site.OutputDir, target.FullPath)
-			if err != nil {
-				continue
-			}
-
-			// Check if matches content pattern
-			if matched, _ := filepath.Match(g.ContentPattern, relPath); !matched {
-				// Try with ** expansion
-				if !strings.Contains(relPath, "blog/") {
-					continue
-				}
-			}
-
-			// Skip index pages (listing pages)
-			if relPath == "blog/index.html" {
+	for _, lang := range slices.Sorted(maps.Keys(langs)) {
+		link := langs[lang]
+		if u, err := url.Parse(link); err != nil || (u.Scheme == "" && !strings.HasPrefix(link, "/")) {
+			target := site.ResolveRef(res, link)
+			if target == nil {
+				site.addUnresolvedRef(res, link)
 				continue
 			}
-
-			// Get metadata from source resource
-			var title, description string
-			var pubDate time.Time
-
-			if res != nil && res.FrontMatter() != nil {
-				fm := res.FrontMatter().Data
-				if t, ok := fm["title"].(string); ok {
-					title = t
-				}
-				if d, ok := fm["description"].(string); ok {
-					description = d
-				}
-				if d, ok := fm["date"].(string); ok {
-					pubDate, _ = time.Parse("2006-01-02", d)
-				}
-				if d, ok := fm["date"].(time.Time); ok {
-					pubDate = d
-				}
-			}
-
-			if title == "" {
-				continue // Skip items without title
+			link = site.resourceLink(target)
+			if path.Ext(link) == "" && !strings.HasSuffix(link, "/") {
+				link += "/"
 			}
-
-			// Build URL path
-			urlPath := "/" + strings.TrimSuffix(relPath, "index.html")
-			urlPath = strings.TrimSuffix(urlPath, "/") + "/"
-
-			g.items = append(g.items, rssItem{
-				Title:       title,
-				Link:        urlPath,
-				Description: description,
-				PubDate:     pubDate,
-				GUID:        urlPath,
-			})
 		}
-	})
+		out = append(out, sitemapAlternateXML{Rel: "alternate", HrefLang: lang, Href: g.absURL(loc, link)})
+	}
+	return
+}
 
-	// Write feed at end of Finalize phase
-	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
-		if err := g.writeFeed(ctx.Site.OutputDir); err != nil {
-			ctx.AddError(fmt.Errorf("RSS generation failed: %w", err))
+// absURL returns the absolute URL of a link on the page at loc.
+func (g *SitemapGenerator) absURL(loc, link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "" {
+		return link
+	}
+	base, _ := url.Parse(loc)
+	return strings.TrimSuffix(g.BaseURL, "/") + base.ResolveReference(u).String()
+}
+
+func (g *SitemapGenerator) shouldExclude(path string) bool {
+	for _, pattern := range g.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
 		}
-	})
+	}
+	return false
 }
 
-func (g *RSSGenerator) writeFeed(outputDir string) error {
-	if len(g.items) == 0 {
+func (g *SitemapGenerator) writeSitemap(site *Site) error {
+	outputDir := site.OutputDir
+	if len(g.urls) == 0 {
 		return nil
 	}
+	sort.Slice(g.urls, func(i, j int) bool {
+		return g.urls[i].Loc < g.urls[j].Loc
+	})
 
-	// Sort by date (newest first) and limit
-	// Simple bubble sort for small lists
-	for i := 0; i < len(g.items)-1; i++ {
-		for j := i + 1; j < len(g.items); j++ {
-			if g.items[j].PubDate.After(g.items[i].PubDate) {
-				g.items[i], g.items[j] = g.items[j], g.items[i]
-			}
-		}
+	if len(g.urls) <= g.MaxURLs {
+		return writeXML(filepath.Join(outputDir, g.OutputPath), g.urlset(g.urls))
 	}
 
-	items := g.items
-	if len(items) > g.MaxItems {
-		items = items[:g.MaxItems]
+	// Too many URLs for one file - split into numbered sitemaps listed in a
+	// sitemap index, eg sitemap-1.xml, sitemap-2.xml
+	ext := filepath.Ext(g.OutputPath)
+	base := strings.TrimSuffix(g.OutputPath, ext)
+	index := sitemapIndexXML{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
 	}
+	for i := 0; i*g.MaxURLs < len(g.urls); i++ {
+		urls := g.urls[i*g.MaxURLs : min((i+1)*g.MaxURLs, len(g.urls))]
+		name := fmt.Sprintf("%s-%d%s", base, i+1, ext)
+		if err := writeXML(filepath.Join(outputDir, name), g.urlset(urls)); err != nil {
+			return err
+		}
 
-	feed := rssXML{
-		Version: "2.0",
-		Channel: rssChannelXML{
-			Title:       g.Title,
-			Link:        g.BaseURL,
-			Description: g.Description,
-		},
+		entry := sitemapIndexEntryXML{
+			Loc: strings.TrimSuffix(g.BaseURL, "/") + site.PathRelUrl("/"+filepath.ToSlash(name)),
+		}
+		var lastMod time.Time
+		for _, u := range urls {
+			if u.LastMod.After(lastMod) {
+				lastMod = u.LastMod
+			}
+		}
+		if !lastMod.IsZero() {
+			entry.LastMod = lastMod.Format("2006-01-02")
+		}
+		index.Sitemaps = append(index.Sitemaps, entry)
 	}
+	log.Printf("[Sitemap] Split %d URLs into %d sitemaps", len(g.urls), len(index.Sitemaps))
+	return writeXML(filepath.Join(outputDir, g.OutputPath), index)
+}
 
-	if len(items) > 0 && !items[0].PubDate.IsZero() {
-		feed.Channel.PubDate = items[0].PubDate.Format(time.RFC1123Z)
+// urlset returns the sitemap for a list of URLs.
+func (g *SitemapGenerator) urlset(urls []sitemapURL) sitemapXML {
+	sitemap := sitemapXML{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
 	}
 
-	for _, item := range items {
-		fullURL := strings.TrimSuffix(g.BaseURL, "/") + item.Link
-		entry := rssItemXML{
-			Title:       item.Title,
-			Link:        fullURL,
-			Description: item.Description,
-			GUID:        fullURL,
+	for _, u := range urls {
+		fullURL := strings.TrimSuffix(g.BaseURL, "/") + u.Loc
+		entry := sitemapURLXML{
+			Loc:        fullURL,
+			ChangeFreq: u.ChangeFreq,
+			Priority:   &u.Priority,
+			Alternates: u.Alternates,
 		}
-		if !item.PubDate.IsZero() {
-			entry.PubDate = item.PubDate.Format(time.RFC1123Z)
+		if !u.LastMod.IsZero() {
+			entry.LastMod = u.LastMod.Format("2006-01-02")
+		}
+		for _, image := range u.Images {
+			entry.Images = append(entry.Images, sitemapImageXML{Loc: image})
+		}
+		if len(entry.Alternates) > 0 {
+			sitemap.XHTMLNS = "http://www.w3.org/1999/xhtml"
+		}
+		if len(entry.Images) > 0 {
+			sitemap.ImageNS = "http://www.google.com/schemas/sitemap-image/1.1"
 		}
-		feed.Channel.Items = append(feed.Channel.Items, entry)
+		sitemap.URLs = append(sitemap.URLs, entry)
 	}
+	return sitemap
+}
 
-	outPath := filepath.Join(outputDir, g.OutputPath)
-	f, err := os.Create(outPath)
+// writeXML writes v as an indented XML document.
+func writeXML(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -431,5 +386,5 @@ func (g *RSSGenerator) writeFeed(outputDir string) error {
 	f.WriteString(xml.Header)
 	enc := xml.NewEncoder(f)
 	enc.Indent("", "  ")
-	return enc.Encode(feed)
+	return enc.Encode(v)
 }
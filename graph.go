@@ -1,6 +1,7 @@
 package s3gen
 
 import (
+	"slices"
 	"time"
 )
 
@@ -31,19 +32,40 @@ func (s *Site) RemoveResource(path string) *Resource {
 	return r
 }
 
+// EdgeKind is the kind of an edge between two resources.
+type EdgeKind int
+
+const (
+	// DependencyEdge is an edge from a resource to one it depends on.
+	// Dependency edges never form cycles.
+	DependencyEdge EdgeKind = iota
+
+	// LinkEdge is an edge from a page to a page it links to. Links between
+	// pages are often cyclic so these are not checked for cycles.
+	LinkEdge
+)
+
+// resEdge is an edge to the resource at Dest.
+type resEdge struct {
+	Dest string
+	Kind EdgeKind
+}
+
+// Returns true if destpath can be reached from srcpath through dependency edges
 func (s *Site) PathExists(srcpath string, destpath string) bool {
 	if s.resedges == nil {
 		return false
 	}
+	visited := map[string]bool{srcpath: true}
 	q := []string{srcpath}
 	for len(q) > 0 {
 		var nq []string
 		for _, p := range q {
-			edges := s.resedges[p]
-			for _, next := range edges {
+			for _, next := range s.edgesFrom(p, DependencyEdge) {
 				if next == destpath {
 					return true
-				} else {
+				} else if !visited[next] {
+					visited[next] = true
 					nq = append(nq, next)
 				}
 			}
@@ -60,53 +82,95 @@ func (s *Site) AddEdge(srcpath string, destpath string) bool {
 	if s.PathExists(destpath, srcpath) {
 		return false
 	}
-	if s.EdgeExists(srcpath, destpath) {
-		return true
-	}
-	s.resedges[srcpath] = append(s.resedges[srcpath], destpath)
+	s.addEdge(srcpath, destpath, DependencyEdge)
 	return true
 }
 
-// Returns true if an edge exists between a source and a destination resource
-func (s *Site) EdgeExists(srcpath string, destpath string) bool {
+// AddLink adds a link edge from the page at srcpath to the page at destpath.
+func (s *Site) AddLink(srcpath string, destpath string) {
+	s.addEdge(srcpath, destpath, LinkEdge)
+}
+
+func (s *Site) addEdge(srcpath string, destpath string, kind EdgeKind) {
+	if s.edgeExists(srcpath, destpath, kind) {
+		return
+	}
 	if s.resedges == nil {
-		s.resedges = make(map[string][]string)
+		s.resedges = make(map[string][]resEdge)
 	}
-	if s.resedges[srcpath] != nil {
-		for _, n := range s.resedges[srcpath] {
-			if n == destpath {
-				// already exists
-				return true
-			}
+	s.resedges[srcpath] = append(s.resedges[srcpath], resEdge{destpath, kind})
+}
+
+// Returns true if a dependency edge exists between a source and a destination resource
+func (s *Site) EdgeExists(srcpath string, destpath string) bool {
+	return s.edgeExists(srcpath, destpath, DependencyEdge)
+}
+
+func (s *Site) edgeExists(srcpath string, destpath string, kind EdgeKind) bool {
+	for _, e := range s.resedges[srcpath] {
+		if e.Dest == destpath && e.Kind == kind {
+			// already exists
+			return true
 		}
 	}
 	return false
 }
 
+// edgesFrom returns the resources srcpath has edges of the given kind to.
+func (s *Site) edgesFrom(srcpath string, kind EdgeKind) (out []string) {
+	for _, e := range s.resedges[srcpath] {
+		if e.Kind == kind {
+			out = append(out, e.Dest)
+		}
+	}
+	return
+}
+
+// edgesTo returns the resources with edges of the given kind to destpath.
+func (s *Site) edgesTo(destpath string, kind EdgeKind) (out []string) {
+	for srcpath := range s.resedges {
+		if s.edgeExists(srcpath, destpath, kind) {
+			out = append(out, srcpath)
+		}
+	}
+	return
+}
+
 // Removes a dependency edge between two resources identified by their full paths
 func (s *Site) RemoveEdge(srcpath string, destpath string) bool {
-	if s.resedges == nil || s.resedges[srcpath] == nil {
+	return s.removeEdges(srcpath, func(e resEdge) bool {
+		return e.Dest == destpath && e.Kind == DependencyEdge
+	})
+}
+
+// RemoveLinksFrom removes the link edges of the page at srcpath.
+func (s *Site) RemoveLinksFrom(srcpath string) {
+	s.removeEdges(srcpath, func(e resEdge) bool {
+		return e.Kind == LinkEdge
+	})
+}
+
+// removeEdges removes the edges from srcpath that match and returns true if
+// any were removed.
+func (s *Site) removeEdges(srcpath string, match func(resEdge) bool) bool {
+	edges := s.resedges[srcpath]
+	if edges == nil {
 		return false
 	}
-	for index, n := range s.resedges[srcpath] {
-		if n == destpath {
-			// already exists
-			slice := s.resedges[srcpath]
-			s.resedges[srcpath] = append(slice[:index], slice[index+1:]...)
-			return true
-		}
-	}
-	return false
+	s.resedges[srcpath] = slices.DeleteFunc(edges, match)
+	return len(s.resedges[srcpath]) < len(edges)
 }
 
-// Removes all resources that a given path depends on
+// Removes all edges (of any kind) to a given path
 func (s *Site) RemoveEdgesTo(destpath string) {
 	for srcpath := range s.resedges {
-		s.RemoveEdge(srcpath, destpath)
+		s.removeEdges(srcpath, func(e resEdge) bool {
+			return e.Dest == destpath
+		})
 	}
 }
 
-// Remove all resources depended by a given path
+// Remove all edges (of any kind) from a given path
 func (s *Site) RemoveEdgesFrom(srcpath string) {
 	if s.resedges != nil {
 		s.resedges[srcpath] = nil
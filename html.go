@@ -3,6 +3,7 @@ package s3gen
 import (
 	"bytes"
 	"fmt"
+	htmpl "html/template"
 	"log"
 	"log/slog"
 	"maps"
@@ -65,12 +66,17 @@ func (m *HTMLToHtml) Run(site *Site, inputs []*Resource, targets []*Resource, fu
 	defer outfile.Close()
 
 	finalmd, err := m.LoadResourceTemplate(site, inres)
+	inres.Document.SetMetadata(renderedContentKey, htmpl.HTML(finalmd))
+	site.collectLinks(inres, htmlLinks(finalmd)...)
 
 	params := map[any]any{
 		"Site":        site,
 		"Res":         inres,
 		"FrontMatter": inres.FrontMatter().Data,
 		"Content":     finalmd,
+		"Paginator":   inres.Paginator,
+		"Item":        inres.Item,
+		"Params":      inres.Params,
 	}
 	if template.Params != nil {
 		maps.Copy(params, template.Params)
@@ -126,14 +132,13 @@ func (h *HTMLToHtml) LoadResourceTemplate(site *Site, r *Resource) ([]byte, erro
 		"Res":         r,
 		"Site":        r.Site,
 		"FrontMatter": r.FrontMatter().Data,
+		"Paginator":   r.Paginator,
+		"Item":        r.Item,
+		"Params":      r.Params,
 	}
 
-	// Include AssetURL function for co-located asset references
-	funcs := map[string]any{
-		"AssetURL": func(filename string) string {
-			return GetAssetURL(r.Site, r, filename)
-		},
-	}
+	// Include AssetURL and ImageSet functions for co-located asset references
+	funcs := resourceFuncs(r)
 
 	finalmd := bytes.NewBufferString("")
 	err = r.Site.Templates.RenderHtmlTemplate(finalmd, template, "", params, funcs)
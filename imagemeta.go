@@ -0,0 +1,313 @@
+package s3gen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImageInfo holds metadata about an image asset. It is read during the
+// Discover phase and stored in the asset's Metadata under the "image" key.
+type ImageInfo struct {
+	// Width and Height are the display dimensions, ie with the EXIF
+	// orientation applied.
+	Width  int
+	Height int
+
+	// Format is the image format, eg "jpeg" or "png".
+	Format string
+
+	// Orientation is the EXIF orientation (1-8). 1 if the image has none.
+	Orientation int
+
+	// TakenAt is the EXIF capture time (DateTimeOriginal). Zero if unknown.
+	TakenAt time.Time
+
+	// DominantColor is the most common colour as a CSS hex value, eg "#a1b2c3".
+	DominantColor string
+
+	// Placeholder is a tiny version of the image as a data: URI, useful as a
+	// low quality placeholder while the full image loads.
+	Placeholder string
+
+	modTime time.Time
+	size    int64
+}
+
+// imageInfoKey is the Resource.Metadata key holding an asset's ImageInfo.
+const imageInfoKey = "image"
+
+// loadAssetMetadata reads image metadata for all image assets of a resource.
+func (s *Site) loadAssetMetadata(res *Resource) {
+	for _, asset := range res.Assets {
+		if !isImagePath(asset.FullPath) {
+			continue
+		}
+		info, err := s.imageInfo(asset)
+		if err != nil {
+			log.Printf("Error reading image metadata for %s: %v", asset.FullPath, err)
+			continue
+		}
+		asset.SetMetadata(imageInfoKey, info)
+	}
+}
+
+// imageInfo returns metadata for an image, reusing the result from a previous
+// build if the file has not changed.
+func (s *Site) imageInfo(res *Resource) (*ImageInfo, error) {
+	stat, err := os.Stat(res.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := s.imageInfos[res.FullPath]; ok && cached.modTime.Equal(stat.ModTime()) && cached.size == stat.Size() {
+		return cached, nil
+	}
+
+	data, err := os.ReadFile(res.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Format:      format,
+		Orientation: 1,
+		modTime:     stat.ModTime(),
+		size:        stat.Size(),
+	}
+	if format == "jpeg" {
+		if orientation, takenAt, err := readEXIF(data); err == nil {
+			if orientation >= 1 && orientation <= 8 {
+				info.Orientation = orientation
+			}
+			info.TakenAt = takenAt
+		}
+	}
+	if info.Orientation >= 5 {
+		// Orientations 5-8 are rotated by 90 degrees
+		info.Width, info.Height = info.Height, info.Width
+	}
+
+	thumb := resizeImage(img, 32, max(1, 32*img.Bounds().Dy()/img.Bounds().Dx()))
+	info.DominantColor = dominantColor(thumb)
+
+	var buf bytes.Buffer
+	placeholder := resizeImage(thumb, 16, max(1, 16*thumb.Bounds().Dy()/thumb.Bounds().Dx()))
+	if err := png.Encode(&buf, placeholder); err == nil {
+		info.Placeholder = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	if s.imageInfos == nil {
+		s.imageInfos = map[string]*ImageInfo{}
+	}
+	s.imageInfos[res.FullPath] = info
+	return info, nil
+}
+
+// dominantColor returns the most common colour in an image, after reducing
+// each channel to 4 bits, as a CSS hex value.
+func dominantColor(img *image.RGBA) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := map[int]*bucket{}
+	var best *bucket
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		if img.Pix[i+3] < 128 {
+			// ignore mostly transparent pixels
+			continue
+		}
+		r, g, b := int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2])
+		key := (r>>4)<<8 | (g>>4)<<4 | (b >> 4)
+		bk := buckets[key]
+		if bk == nil {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.count++
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		if best == nil || bk.count > best.count {
+			best = bk
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
+
+// readEXIF extracts the orientation and capture time from the EXIF block of
+// a JPEG file.
+func readEXIF(data []byte) (orientation int, takenAt time.Time, err error) {
+	tiff, err := findEXIF(data)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(tiff) < 8 {
+		return 0, time.Time{}, fmt.Errorf("exif: short header")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, time.Time{}, fmt.Errorf("exif: invalid byte order")
+	}
+
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if v, ok := ifd0[0x0112]; ok {
+		orientation = int(order.Uint16(v.value))
+	}
+
+	dateTime := ""
+	if v, ok := ifd0[0x8769]; ok {
+		exifIFD := readIFD(tiff, order, order.Uint32(v.value))
+		if v, ok := exifIFD[0x9003]; ok {
+			dateTime = v.ascii(tiff, order)
+		}
+	}
+	if dateTime == "" {
+		if v, ok := ifd0[0x0132]; ok {
+			dateTime = v.ascii(tiff, order)
+		}
+	}
+	if dateTime != "" {
+		takenAt, _ = time.Parse("2006:01:02 15:04:05", dateTime)
+	}
+	return orientation, takenAt, nil
+}
+
+// findEXIF returns the TIFF structure inside the APP1 segment of a JPEG.
+func findEXIF(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("exif: not a jpeg")
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return nil, fmt.Errorf("exif: invalid marker")
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// start of scan or end of image - no more metadata
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		pos = end
+	}
+	return nil, io.EOF
+}
+
+type exifEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// ascii returns the string value of an ASCII entry.
+func (e exifEntry) ascii(tiff []byte, order binary.ByteOrder) string {
+	if e.typ != 2 {
+		return ""
+	}
+	raw := e.value
+	if e.count > 4 {
+		off := order.Uint32(e.value)
+		if uint64(off)+uint64(e.count) > uint64(len(tiff)) {
+			return ""
+		}
+		raw = tiff[off : off+e.count]
+	}
+	return strings.TrimRight(string(raw), "\x00 ")
+}
+
+// readIFD reads the entries of the IFD at the given offset, keyed by tag.
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16]exifEntry {
+	out := map[uint16]exifEntry{}
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return out
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	pos := int(offset) + 2
+	for i := 0; i < count && pos+12 <= len(tiff); i++ {
+		out[order.Uint16(tiff[pos:])] = exifEntry{
+			typ:   order.Uint16(tiff[pos+2:]),
+			count: order.Uint32(tiff[pos+4:]),
+			value: tiff[pos+8 : pos+12],
+		}
+		pos += 12
+	}
+	return out
+}
+
+// ImageInfoOf returns the image metadata of an asset resource, or nil if it
+// is not an image.
+func ImageInfoOf(res *Resource) *ImageInfo {
+	if res == nil {
+		return nil
+	}
+	info, _ := res.Metadata[imageInfoKey].(*ImageInfo)
+	return info
+}
+
+// GetImageInfo returns the metadata of a co-located image of res by file name.
+func GetImageInfo(res *Resource, filename string) *ImageInfo {
+	for _, asset := range res.Assets {
+		if filepath.Base(asset.FullPath) == filename {
+			return ImageInfoOf(asset)
+		}
+	}
+	return nil
+}
+
+// ImagesByDate returns the image assets of a resource sorted by their EXIF
+// capture time. Images without a capture time are sorted by file name at
+// the end.
+func ImagesByDate(res *Resource, desc bool) (out []*Resource) {
+	for _, asset := range res.Assets {
+		if ImageInfoOf(asset) != nil {
+			out = append(out, asset)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		t1, t2 := ImageInfoOf(out[i]).TakenAt, ImageInfoOf(out[j]).TakenAt
+		if t1.IsZero() || t2.IsZero() {
+			if t1.IsZero() && t2.IsZero() {
+				return out[i].FullPath < out[j].FullPath
+			}
+			return !t1.IsZero()
+		}
+		if desc {
+			return t1.After(t2)
+		}
+		return t1.Before(t2)
+	})
+	return
+}
@@ -0,0 +1,193 @@
+package s3gen
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// tiffEntry is an IFD entry for building test EXIF blocks. Values longer
+// than 4 bytes are stored after the IFDs.
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func shortEntry(order binary.ByteOrder, tag, value uint16) tiffEntry {
+	b := make([]byte, 4)
+	order.PutUint16(b, value)
+	return tiffEntry{tag, 3, 1, b}
+}
+
+func asciiEntry(tag uint16, value string) tiffEntry {
+	return tiffEntry{tag, 2, uint32(len(value) + 1), append([]byte(value), 0)}
+}
+
+// buildTIFF lays out a TIFF header, IFD0 and, if there are exif entries,
+// the Exif IFD that IFD0 points to.
+func buildTIFF(order binary.ByteOrder, ifd0, exif []tiffEntry) []byte {
+	ifdSize := func(n int) int { return 2 + 12*n + 4 }
+	if exif != nil {
+		ifd0 = append(ifd0, tiffEntry{0x8769, 4, 1, make([]byte, 4)})
+	}
+	exifOffset := 8 + ifdSize(len(ifd0))
+	dataOffset := exifOffset
+	if exif != nil {
+		order.PutUint32(ifd0[len(ifd0)-1].value, uint32(exifOffset))
+		dataOffset += ifdSize(len(exif))
+	}
+
+	out := make([]byte, dataOffset)
+	if order == binary.LittleEndian {
+		copy(out, "II")
+	} else {
+		copy(out, "MM")
+	}
+	order.PutUint16(out[2:], 42)
+	order.PutUint32(out[4:], 8)
+	writeIFD := func(offset int, entries []tiffEntry) {
+		order.PutUint16(out[offset:], uint16(len(entries)))
+		pos := offset + 2
+		for _, e := range entries {
+			order.PutUint16(out[pos:], e.tag)
+			order.PutUint16(out[pos+2:], e.typ)
+			order.PutUint32(out[pos+4:], e.count)
+			if len(e.value) > 4 {
+				order.PutUint32(out[pos+8:], uint32(len(out)))
+				out = append(out, e.value...)
+			} else {
+				copy(out[pos+8:pos+12], e.value)
+			}
+			pos += 12
+		}
+	}
+	writeIFD(8, ifd0)
+	if exif != nil {
+		writeIFD(exifOffset, exif)
+	}
+	return out
+}
+
+// exifJPEG wraps a TIFF block in the APP1 segment of a minimal JPEG.
+func exifJPEG(tiff []byte) []byte {
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(2+6+len(tiff)))
+	out = append(out, "Exif\x00\x00"...)
+	out = append(out, tiff...)
+	return append(out, 0xFF, 0xD9)
+}
+
+func TestReadEXIF(t *testing.T) {
+	taken := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tests := []struct {
+			name        string
+			data        []byte
+			orientation int
+			takenAt     time.Time
+			wantErr     bool
+		}{{
+			name: "orientation and DateTimeOriginal",
+			data: exifJPEG(buildTIFF(order,
+				[]tiffEntry{shortEntry(order, 0x0112, 6)},
+				[]tiffEntry{asciiEntry(0x9003, "2023:04:05 06:07:08")})),
+			orientation: 6,
+			takenAt:     taken,
+		}, {
+			name: "DateTime in IFD0",
+			data: exifJPEG(buildTIFF(order,
+				[]tiffEntry{asciiEntry(0x0132, "2023:04:05 06:07:08")}, nil)),
+			takenAt: taken,
+		}, {
+			name: "DateTimeOriginal takes precedence",
+			data: exifJPEG(buildTIFF(order,
+				[]tiffEntry{asciiEntry(0x0132, "2020:01:01 00:00:00")},
+				[]tiffEntry{asciiEntry(0x9003, "2023:04:05 06:07:08")})),
+			takenAt: taken,
+		}, {
+			name: "truncated IFD",
+			data: exifJPEG(buildTIFF(order,
+				[]tiffEntry{shortEntry(order, 0x0112, 3), asciiEntry(0x0132, "2023:04:05 06:07:08")}, nil)[:8+2+12]),
+			orientation: 3,
+		}, {
+			name: "IFD0 offset out of range",
+			data: exifJPEG(func() []byte {
+				tiff := buildTIFF(order, []tiffEntry{shortEntry(order, 0x0112, 6)}, nil)
+				order.PutUint32(tiff[4:], 0xFFFFFFF0)
+				return tiff
+			}()),
+		}, {
+			name: "Exif IFD offset out of range",
+			data: exifJPEG(func() []byte {
+				tiff := buildTIFF(order, nil, []tiffEntry{asciiEntry(0x9003, "2023:04:05 06:07:08")})
+				order.PutUint32(tiff[8+2+8:], 0xFFFFFFF0)
+				return tiff
+			}()),
+		}, {
+			name: "ASCII value offset out of range",
+			data: exifJPEG(func() []byte {
+				tiff := buildTIFF(order, []tiffEntry{asciiEntry(0x0132, "2023:04:05 06:07:08")}, nil)
+				order.PutUint32(tiff[8+2+8:], uint32(len(tiff)-4))
+				return tiff
+			}()),
+		}, {
+			name:    "short TIFF header",
+			data:    exifJPEG(buildTIFF(order, nil, nil)[:6]),
+			wantErr: true,
+		}}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				orientation, takenAt, err := readEXIF(test.data)
+				if (err != nil) != test.wantErr {
+					t.Fatalf("readEXIF() error = %v, wantErr %v", err, test.wantErr)
+				}
+				if orientation != test.orientation {
+					t.Errorf("orientation = %d, want %d", orientation, test.orientation)
+				}
+				if !takenAt.Equal(test.takenAt) {
+					t.Errorf("takenAt = %v, want %v", takenAt, test.takenAt)
+				}
+			})
+		}
+	}
+}
+
+func TestReadEXIFInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a jpeg", []byte("GIF89a......")},
+		{"no exif segment", []byte{0xFF, 0xD8, 0xFF, 0xD9}},
+		{"invalid byte order", exifJPEG([]byte("XX\x00\x2a\x00\x00\x00\x08"))},
+		{"segment length past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := readEXIF(test.data); err == nil {
+				t.Errorf("readEXIF() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestReadIFD(t *testing.T) {
+	order := binary.LittleEndian
+	tiff := buildTIFF(order, []tiffEntry{shortEntry(order, 0x0112, 8), shortEntry(order, 0x0100, 640)}, nil)
+
+	if got := readIFD(tiff, order, 8); len(got) != 2 || order.Uint16(got[0x0112].value) != 8 {
+		t.Errorf("readIFD() = %v, want 2 entries with orientation 8", got)
+	}
+	// The count claims 2 entries but only the first is in the data
+	if got := readIFD(tiff[:8+2+12+4], order, 8); len(got) != 1 {
+		t.Errorf("readIFD() of a truncated IFD = %v, want 1 entry", got)
+	}
+	for _, offset := range []uint32{uint32(len(tiff)), uint32(len(tiff) - 1), 0xFFFFFFFF} {
+		if got := readIFD(tiff, order, offset); len(got) != 0 {
+			t.Errorf("readIFD() at %d = %v, want no entries", offset, got)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ImageTransform generates resized copies of co-located images during the
+// Transform phase so pages can serve responsive images via srcset. Only the
+// standard library decoders are used, so JPEG, PNG and GIF are supported.
+//
+// Variants are written next to the copied original with the width appended to
+// the name, eg hero.jpg -> hero-480w.jpg, hero-960w.jpg. Images are never
+// upscaled. Templates can get the generated variants with the ImageSet
+// function.
+type ImageTransform struct {
+	// Widths are the widths (in pixels) to generate (default: 480, 960, 1440)
+	Widths []int
+
+	// Quality is the JPEG encoding quality (default: 82)
+	Quality int
+
+	// CacheDir is where resized images are cached, keyed by the content hash of
+	// the source image, the width and the quality. Defaults to "s3gen/images" in the user's
+	// cache directory. Set to "-" to disable caching.
+	CacheDir string
+}
+
+// ImageVariant is a single resized copy of an image.
+type ImageVariant struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// ImageSet describes an image and its responsive variants.
+type ImageSet struct {
+	// Src is the URL of the original image.
+	Src string
+
+	// Width and Height are the dimensions of the original image.
+	Width  int
+	Height int
+
+	// Variants are the resized copies, smallest first.
+	Variants []ImageVariant
+}
+
+// SrcSet returns the value for a srcset attribute covering all variants and
+// the original image.
+func (i *ImageSet) SrcSet() string {
+	var parts []string
+	for _, v := range i.Variants {
+		parts = append(parts, fmt.Sprintf("%s %dw", v.URL, v.Width))
+	}
+	if i.Width > 0 {
+		parts = append(parts, fmt.Sprintf("%s %dw", i.Src, i.Width))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ImageSource is the srcset of the variants of an image in one format.
+type ImageSource struct {
+	Type   string
+	SrcSet string
+}
+
+// Sources returns a srcset per format of the variants, with the original
+// image included in the srcset of its format. Use these for the <source>
+// elements of a <picture>.
+func (i *ImageSet) Sources() (out []ImageSource) {
+	var types []string
+	parts := map[string][]string{}
+	add := func(url string, width int) {
+		t := mediaType(url)
+		if _, ok := parts[t]; !ok {
+			types = append(types, t)
+		}
+		parts[t] = append(parts[t], fmt.Sprintf("%s %dw", url, width))
+	}
+	for _, v := range i.Variants {
+		add(v.URL, v.Width)
+	}
+	if i.Width > 0 {
+		add(i.Src, i.Width)
+	}
+	for _, t := range types {
+		out = append(out, ImageSource{Type: t, SrcSet: strings.Join(parts[t], ", ")})
+	}
+	return
+}
+
+// imageVariant is a generated variant, with its path relative to OutputDir.
+type imageVariant struct {
+	relpath string
+	width   int
+	height  int
+}
+
+// imageExtensions are the file extensions that can be decoded and resized.
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+func isImagePath(path string) bool {
+	return slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+func (t *ImageTransform) Phase() BuildPhase {
+	return PhaseTransform
+}
+
+func (t *ImageTransform) DependsOn() []string {
+	return []string{"**/*.jpg", "**/*.jpeg", "**/*.png", "**/*.gif"}
+}
+
+func (t *ImageTransform) Produces() []string {
+	return []string{"**/*w.jpg", "**/*w.jpeg", "**/*w.png", "**/*w.gif"}
+}
+
+// TransformsAssets returns true - ImageTransform only processes co-located assets.
+func (t *ImageTransform) TransformsAssets() bool {
+	return true
+}
+
+func (t *ImageTransform) widths() []int {
+	if len(t.Widths) == 0 {
+		return []int{480, 960, 1440}
+	}
+	widths := slices.Clone(t.Widths)
+	slices.Sort(widths)
+	return widths
+}
+
+// variantWidths returns the configured widths that are smaller than the source.
+func (t *ImageTransform) variantWidths(srcWidth int) (out []int) {
+	for _, w := range t.widths() {
+		if w > 0 && w < srcWidth {
+			out = append(out, w)
+		}
+	}
+	return
+}
+
+func (t *ImageTransform) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	if res.AssetOf == nil || !isImagePath(res.FullPath) {
+		return nil, nil
+	}
+
+	config, err := decodeImageConfig(res.FullPath)
+	if err != nil {
+		log.Printf("Error reading image %s: %v", res.FullPath, err)
+		return nil, nil
+	}
+
+	// Resizing would keep only the first frame, so animations are left as is
+	if isAnimatedGif(res.FullPath) {
+		return nil, nil
+	}
+
+	destPath := assetDestPath(site, res.AssetOf, res)
+	if destPath == "" {
+		return nil, nil
+	}
+	ext := filepath.Ext(destPath)
+	base := destPath[:len(destPath)-len(ext)]
+
+	var targets []*Resource
+	for _, w := range t.variantWidths(config.Width) {
+		target := site.GetResource(filepath.Join(site.OutputDir, fmt.Sprintf("%s-%dw%s", base, w, ext)))
+		target.Source = res
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	return []*Resource{res}, targets
+}
+
+func (t *ImageTransform) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	if len(inputs) != 1 {
+		return fmt.Errorf("ImageTransform: expected 1 input, got %d", len(inputs))
+	}
+
+	input := inputs[0]
+	config, err := decodeImageConfig(input.FullPath)
+	if err != nil {
+		return fmt.Errorf("ImageTransform: failed to read %s: %w", input.FullPath, err)
+	}
+
+	widths := t.variantWidths(config.Width)
+	if len(widths) != len(targets) {
+		return fmt.Errorf("ImageTransform: expected %d targets for %s, got %d", len(widths), input.FullPath, len(targets))
+	}
+
+	hash := contentHash(input)
+	ext := strings.ToLower(filepath.Ext(input.FullPath))
+	var src image.Image
+	var variants []imageVariant
+
+	for i, target := range targets {
+		w := widths[i]
+		h := max(1, config.Height*w/config.Width)
+		target.EnsureDir()
+
+		relpath, _ := filepath.Rel(site.OutputDir, target.FullPath)
+		variants = append(variants, imageVariant{relpath: relpath, width: w, height: h})
+
+		// Reuse a previously resized copy of the same content
+		cachePath := t.cachePath(hash, w, ext)
+		if cachePath != "" {
+			if data, err := os.ReadFile(cachePath); err == nil {
+				if err := os.WriteFile(target.FullPath, data, 0644); err != nil {
+					return fmt.Errorf("ImageTransform: failed to write %s: %w", target.FullPath, err)
+				}
+				continue
+			}
+		}
+
+		if src == nil {
+			f, err := os.Open(input.FullPath)
+			if err != nil {
+				return err
+			}
+			src, _, err = image.Decode(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("ImageTransform: failed to decode %s: %w", input.FullPath, err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := t.encode(&buf, resizeImage(src, w, h), ext); err != nil {
+			return fmt.Errorf("ImageTransform: failed to encode %s: %w", target.FullPath, err)
+		}
+		if err := os.WriteFile(target.FullPath, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("ImageTransform: failed to write %s: %w", target.FullPath, err)
+		}
+		if cachePath != "" {
+			if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+				os.WriteFile(cachePath, buf.Bytes(), 0644)
+			}
+		}
+	}
+
+	if site.imageSets == nil {
+		site.imageSets = map[string][]imageVariant{}
+	}
+	site.imageSets[input.FullPath] = variants
+	log.Printf("[Images] %s -> %d variants", input.FullPath, len(variants))
+	return nil
+}
+
+func (t *ImageTransform) cachePath(hash string, width int, ext string) string {
+	dir := t.CacheDir
+	if dir == "-" || hash == "" {
+		return ""
+	}
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(userCache, "s3gen", "images")
+	}
+	return filepath.Join(dir, hash[:2], hash+"-"+strconv.Itoa(width)+"-q"+strconv.Itoa(t.quality())+ext)
+}
+
+func (t *ImageTransform) quality() int {
+	if t.Quality <= 0 {
+		return 82
+	}
+	return t.Quality
+}
+
+func (t *ImageTransform) encode(buf *bytes.Buffer, img image.Image, ext string) error {
+	switch ext {
+	case ".png":
+		return png.Encode(buf, img)
+	case ".gif":
+		return gif.Encode(buf, img, nil)
+	default:
+		return jpeg.Encode(buf, img, &jpeg.Options{Quality: t.quality()})
+	}
+}
+
+// isAnimatedGif returns true if path is a GIF with more than one frame.
+func isAnimatedGif(path string) bool {
+	if strings.ToLower(filepath.Ext(path)) != ".gif" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	return err == nil && len(g.Image) > 1
+}
+
+// decodeImageConfig reads just the header of an image to get its dimensions.
+func decodeImageConfig(path string) (image.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	return config, err
+}
+
+// resizeImage scales src to width x height by averaging the source pixels
+// covered by each destination pixel. This is well suited to downscaling.
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	}
+	sb := rgba.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy0 := y * sh / height
+		sy1 := max(sy0+1, (y+1)*sh/height)
+		for x := 0; x < width; x++ {
+			sx0 := x * sw / width
+			sx1 := max(sx0+1, (x+1)*sw/width)
+
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				off := rgba.PixOffset(sb.Min.X+sx0, sb.Min.Y+sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(rgba.Pix[off])
+					g += uint32(rgba.Pix[off+1])
+					bl += uint32(rgba.Pix[off+2])
+					a += uint32(rgba.Pix[off+3])
+					n++
+					off += 4
+				}
+			}
+			doff := dst.PixOffset(x, y)
+			dst.Pix[doff] = uint8(r / n)
+			dst.Pix[doff+1] = uint8(g / n)
+			dst.Pix[doff+2] = uint8(bl / n)
+			dst.Pix[doff+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// GetImageSet returns the original and resized variants of a co-located
+// image of res. Variants are only available if an ImageTransform rule has
+// processed the image; otherwise just the original is returned.
+func GetImageSet(site *Site, res *Resource, filename string) (*ImageSet, error) {
+	for _, asset := range res.Assets {
+		if filepath.Base(asset.FullPath) != filename {
+			continue
+		}
+
+		out := &ImageSet{Src: site.Asset(assetDestPath(site, res, asset))}
+		if config, err := decodeImageConfig(asset.FullPath); err == nil {
+			out.Width = config.Width
+			out.Height = config.Height
+		}
+		for _, v := range site.imageSets[asset.FullPath] {
+			out.Variants = append(out.Variants, ImageVariant{
+				URL:    site.Asset(v.relpath),
+				Width:  v.width,
+				Height: v.height,
+			})
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("no co-located image %s found for %s", filename, res.FullPath)
+}
@@ -0,0 +1,273 @@
+package s3gen
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LinkChecker verifies internal links in the Finalize phase. Every generated
+// HTML page is scanned and the href and src attributes pointing within the
+// site are checked to resolve to files in OutputDir. Links with a #fragment
+// are also checked against the element ids on the target page, which
+// includes the heading ids used by the table of contents.
+type LinkChecker struct {
+	// ExcludePatterns are glob patterns (relative to OutputDir) for pages
+	// that should not be checked.
+	ExcludePatterns []string
+
+	// IgnorePrefixes are URL path prefixes that are not checked, eg "/api/"
+	// for paths served by something other than the site.
+	IgnorePrefixes []string
+
+	// SkipFragments disables checking #fragment links against element ids.
+	SkipFragments bool
+
+	// WarnOnly reports broken links as warnings instead of build errors.
+	WarnOnly bool
+
+	// ReportOrphans reports pages that no other page links to as warnings.
+	ReportOrphans bool
+
+	// Broken holds the broken links found in the last build.
+	Broken []BrokenLink
+
+	// Orphans holds the URLs of orphan pages found in the last build, if
+	// ReportOrphans is set.
+	Orphans []string
+
+	// ids found on each page, keyed by path relative to OutputDir
+	ids map[string]map[string]bool
+}
+
+// BrokenLink is a link that does not resolve.
+type BrokenLink struct {
+	// Page is the path of the page containing the link, relative to OutputDir.
+	Page string
+
+	// Link is the link as it appears in the page.
+	Link string
+
+	// Reason describes why the link is broken.
+	Reason string
+}
+
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("%s: broken link %q (%s)", b.Page, b.Link, b.Reason)
+}
+
+// Phase returns PhaseFinalize - links are checked after all content is generated.
+func (c *LinkChecker) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns patterns for HTML files - all pages must be generated first.
+func (c *LinkChecker) DependsOn() []string {
+	return []string{"**/*.html"}
+}
+
+// Produces returns nil - LinkChecker only reports problems.
+func (c *LinkChecker) Produces() []string {
+	return nil
+}
+
+// TargetsFor returns nil - LinkChecker uses hooks instead of per-resource targets.
+func (c *LinkChecker) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (c *LinkChecker) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the link checker to a site. Links are checked at the end of
+// the Finalize phase, so files written by generators registered earlier are
+// also checked.
+func (c *LinkChecker) Register(site *Site) {
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		if err := c.check(ctx); err != nil {
+			ctx.AddError(fmt.Errorf("link check failed: %w", err))
+		}
+	})
+}
+
+func (c *LinkChecker) check(ctx *BuildContext) error {
+	site := ctx.Site
+	c.Broken = nil
+	c.Orphans = nil
+	c.ids = map[string]map[string]bool{}
+
+	// Find all pages
+	var pages []string
+	err := filepath.WalkDir(site.OutputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".html") {
+			relpath, _ := filepath.Rel(site.OutputDir, path)
+			pages = append(pages, filepath.ToSlash(relpath))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(pages)
+
+	linked := map[string]bool{}
+	checked := 0
+	for _, page := range pages {
+		if c.shouldExclude(page) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(site.OutputDir, page))
+		if err != nil {
+			return err
+		}
+		checked++
+		for _, link := range htmlLinks(data, "href", "src") {
+			target, reason := c.checkLink(site, page, link)
+			if reason != "" {
+				c.Broken = append(c.Broken, BrokenLink{Page: page, Link: link, Reason: reason})
+			} else if target != "" && target != page {
+				linked[target] = true
+			}
+		}
+	}
+
+	for _, broken := range c.Broken {
+		if c.WarnOnly {
+			ctx.AddWarning(fmt.Errorf("%s", broken))
+		} else {
+			ctx.AddError(fmt.Errorf("%s", broken))
+		}
+	}
+
+	if c.ReportOrphans {
+		for _, page := range pages {
+			if linked[page] || page == "index.html" || page == "404.html" || c.shouldExclude(page) {
+				continue
+			}
+			orphan := site.PathRelUrl("/" + strings.TrimSuffix(page, "index.html"))
+			c.Orphans = append(c.Orphans, orphan)
+			ctx.AddWarning(fmt.Errorf("orphan page: %s is not linked from any page", orphan))
+		}
+	}
+
+	log.Printf("[LinkChecker] Checked %d pages, %d broken links", checked, len(c.Broken))
+	return nil
+}
+
+// checkLink checks a link on a page. Returns the page it points to (relative
+// to OutputDir) if it is an internal link, and the reason if it is broken.
+func (c *LinkChecker) checkLink(site *Site, page, link string) (target string, reason string) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", "invalid URL"
+	}
+	if u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		return "", ""
+	}
+
+	if u.Path == "" {
+		// Fragment on the same page
+		target = page
+	} else {
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			// Relative links are relative to the URL the page is served at
+			p = path.Join(site.PathPrefix, "/", path.Dir(filepath.ToSlash(page)), p)
+			if strings.HasSuffix(u.Path, "/") {
+				p += "/"
+			}
+		}
+		for _, prefix := range c.IgnorePrefixes {
+			if strings.HasPrefix(p, prefix) {
+				return "", ""
+			}
+		}
+		if site.PathPrefix != "" && site.PathPrefix != "/" {
+			rest, ok := cutPathPrefix(p, site.PathPrefix)
+			if !ok {
+				return "", "outside of PathPrefix"
+			}
+			p = rest
+		}
+		if target = resolveOutputFile(site.OutputDir, p); target == "" {
+			return "", "no such file"
+		}
+	}
+
+	if u.Fragment != "" && !c.SkipFragments && strings.HasSuffix(target, ".html") {
+		ids, err := c.pageIDs(site, target)
+		if err != nil {
+			return target, err.Error()
+		}
+		if !ids[u.Fragment] {
+			return target, fmt.Sprintf("no element with id %q", u.Fragment)
+		}
+	}
+	return target, ""
+}
+
+// resolveOutputFile returns the file (relative to outputDir) served for a
+// URL path, or "" if there is none.
+func resolveOutputFile(outputDir, urlPath string) string {
+	rel := strings.TrimPrefix(urlPath, "/")
+	candidates := []string{rel}
+	if rel == "" || strings.HasSuffix(rel, "/") {
+		candidates = []string{rel + "index.html"}
+	} else if filepath.Ext(rel) == "" {
+		candidates = append(candidates, rel+"/index.html", rel+".html")
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(c))); err == nil {
+			if info.IsDir() {
+				c = strings.TrimSuffix(c, "/") + "/index.html"
+				if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(c))); err != nil {
+					continue
+				}
+			}
+			return c
+		}
+	}
+	return ""
+}
+
+// pageIDs returns the element ids (and anchor names) on a page.
+func (c *LinkChecker) pageIDs(site *Site, page string) (map[string]bool, error) {
+	if ids, ok := c.ids[page]; ok {
+		return ids, nil
+	}
+	data, err := os.ReadFile(filepath.Join(site.OutputDir, filepath.FromSlash(page)))
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]bool{}
+	for _, id := range htmlAttrValues(data, "id", "name") {
+		ids[id] = true
+	}
+	c.ids[page] = ids
+	return ids, nil
+}
+
+func (c *LinkChecker) shouldExclude(path string) bool {
+	for _, pattern := range c.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+	}
+	return false
+}
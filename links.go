@@ -0,0 +1,191 @@
+package s3gen
+
+import (
+	"html"
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// htmlAttrPattern matches attributes in HTML markup. Group 1 is the name and
+// groups 2, 3 or 4 hold the value depending on how it is quoted.
+var htmlAttrPattern = regexp.MustCompile(`(?i)\s([a-z][a-z0-9_:-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// htmlAttrValues returns the unescaped values of the given attributes
+// (eg "href", "src") found in HTML markup, in document order.
+func htmlAttrValues(data []byte, names ...string) (out []string) {
+	for _, m := range htmlAttrPattern.FindAllSubmatch(data, -1) {
+		if !slices.Contains(names, strings.ToLower(string(m[1]))) {
+			continue
+		}
+		value := m[2]
+		if value == nil {
+			value = m[3]
+		}
+		if value == nil {
+			value = m[4]
+		}
+		out = append(out, html.UnescapeString(string(value)))
+	}
+	return
+}
+
+// nonMarkupPattern matches comments and the content of elements that is not
+// markup, so attributes shown in code samples are not mistaken for real ones.
+// The opening tag (group 1) is kept, eg for <script src="...">.
+var nonMarkupPattern = regexp.MustCompile(`(?is)<!--.*?-->|(<(?:pre|code|script|style|textarea)\b[^>]*>).*?</(?:pre|code|script|style|textarea)>`)
+
+// htmlLinks returns the values of the given link attributes (default "href")
+// in HTML markup, ignoring code samples, scripts and comments.
+func htmlLinks(data []byte, names ...string) []string {
+	if len(names) == 0 {
+		names = []string{"href"}
+	}
+	return htmlAttrValues(nonMarkupPattern.ReplaceAll(data, []byte("$1")), names...)
+}
+
+// normalizeLinkURL resolves a link found on the page served at pageURL to an
+// absolute path on the site, without query or fragment. Returns "" for
+// external links and links without a path.
+func normalizeLinkURL(pageURL, link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	p := base.ResolveReference(&url.URL{Path: u.Path}).Path
+	p = strings.TrimSuffix(p, "index.html")
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+// collectLinks records links found in the content of page while it is
+// rendered. Links of the link pass are not collected as they are the same,
+// besides those printed from the link graph itself.
+func (s *Site) collectLinks(page *Resource, links ...string) {
+	if s.renderingLinkPass {
+		return
+	}
+	if s.pageLinks == nil {
+		s.pageLinks = map[string][]string{}
+	}
+	for _, link := range links {
+		if !slices.Contains(s.pageLinks[page.FullPath], link) {
+			s.pageLinks[page.FullPath] = append(s.pageLinks[page.FullPath], link)
+		}
+	}
+}
+
+// buildLinkGraph indexes the URLs of the pages generated in this build and
+// adds the links collected from their content to the resource graph as link
+// edges.
+func (s *Site) buildLinkGraph(ctx *BuildContext) {
+	if s.pageURLs == nil {
+		s.pageURLs = map[string]string{}
+	}
+	var pages []*Resource
+	for _, t := range ctx.GeneratedTargets {
+		if t.Source == nil || filepath.Ext(t.FullPath) != ".html" {
+			continue
+		}
+		s.pageURLs[normalizeLinkURL("/", s.TargetURL(t))] = t.Source.FullPath
+		pages = append(pages, t)
+	}
+
+	// Links of the pages in this build replace their earlier links. Pages
+	// with several outputs (eg paginated lists) resolve relative links
+	// against each of them.
+	for _, t := range pages {
+		s.RemoveLinksFrom(t.Source.FullPath)
+	}
+	for _, t := range pages {
+		srcpath := t.Source.FullPath
+		pageURL := s.TargetURL(t)
+		for _, link := range s.pageLinks[srcpath] {
+			destpath, ok := s.pageURLs[normalizeLinkURL(pageURL, link)]
+			if ok && destpath != srcpath {
+				s.AddLink(srcpath, destpath)
+			}
+		}
+	}
+}
+
+// OutgoingLinks returns the pages that res links to.
+func (s *Site) OutgoingLinks(res *Resource) []*Resource {
+	return s.linkedResources(s.edgesFrom(res.FullPath, LinkEdge))
+}
+
+// Backlinks returns the pages that link to res.
+func (s *Site) Backlinks(res *Resource) []*Resource {
+	return s.linkedResources(s.edgesTo(res.FullPath, LinkEdge))
+}
+
+func (s *Site) linkedResources(paths []string) (out []*Resource) {
+	for _, p := range paths {
+		if res, ok := s.resources[p]; ok {
+			out = append(out, res)
+		}
+	}
+	sortByPath(out)
+	return
+}
+
+func sortByPath(resources []*Resource) {
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].FullPath < resources[j].FullPath
+	})
+}
+
+// linkGraphFunc wraps a link graph lookup for use while rendering page.
+// Pages that use the link graph during Generate are rendered again once the
+// graph is complete, so until then the lookup returns no pages. This way the
+// links printed from the graph are never collected as links of the page.
+func (s *Site) linkGraphFunc(page *Resource, lookup func(*Resource) []*Resource) func(*Resource) []*Resource {
+	return func(res *Resource) []*Resource {
+		if ctx := s.buildCtx; ctx != nil && ctx.CurrentPhase == PhaseGenerate && !s.renderingLinkPass {
+			if s.linkGraphReaders == nil {
+				s.linkGraphReaders = map[string]bool{}
+			}
+			s.linkGraphReaders[page.FullPath] = true
+			return nil
+		}
+		return lookup(res)
+	}
+}
+
+// pendingRender is a rule run to be repeated after the link graph is built.
+type pendingRender struct {
+	res     *Resource
+	rule    Rule
+	inputs  []*Resource
+	targets []*Resource
+}
+
+// renderLinkPass re-renders the pages that used the link graph before it
+// was complete.
+func (s *Site) renderLinkPass(ctx *BuildContext) {
+	if len(s.linkPassRenders) == 0 {
+		return
+	}
+	log.Printf("Re-rendering %d pages that use the link graph", len(s.linkPassRenders))
+	s.renderingLinkPass = true
+	defer func() {
+		s.renderingLinkPass = false
+		s.linkPassRenders = nil
+		s.linkGraphReaders = nil
+	}()
+	for _, r := range s.linkPassRenders {
+		if err := r.rule.Run(s, r.inputs, r.targets, stageFuncs(r.res)); err != nil {
+			ctx.AddError(err)
+		}
+	}
+}
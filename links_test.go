@@ -0,0 +1,29 @@
+package s3gen
+
+import "testing"
+
+func TestNormalizeLinkURL(t *testing.T) {
+	tests := []struct {
+		pageURL, link, want string
+	}{
+		{"/blog/post/", "/docs/setup/", "/docs/setup"},
+		{"/blog/post/", "../other/", "/blog/other"},
+		{"/blog/post/", "img/", "/blog/post/img"},
+		{"/blog/post", "other", "/blog/other"},
+		{"/blog/post/", "/docs/setup/#install", "/docs/setup"},
+		{"/blog/post/", "/search/?q=go", "/search"},
+		{"/blog/post/", "/blog/index.html", "/blog"},
+		{"/blog/post/", "/", "/"},
+		{"/blog/post/", "  /docs/  ", "/docs"},
+		{"/blog/post/", "#heading", ""},
+		{"/blog/post/", "?page=2", ""},
+		{"/blog/post/", "https://example.com/docs/", ""},
+		{"/blog/post/", "//example.com/docs/", ""},
+		{"/blog/post/", "mailto:me@example.com", ""},
+	}
+	for _, test := range tests {
+		if got := normalizeLinkURL(test.pageURL, test.link); got != test.want {
+			t.Errorf("normalizeLinkURL(%q, %q) = %q, want %q", test.pageURL, test.link, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// LLMsTxtGenerator writes an llms.txt (see https://llmstxt.org) in the
+// Finalize phase, listing the pages of the site grouped by section. The
+// markdown of each page is also written next to its HTML as index.md, so
+// tools can read the content of the site without scraping HTML. llms.txt
+// links to these renditions.
+type LLMsTxtGenerator struct {
+	// Title is the name of the site, used as the heading of llms.txt
+	Title string
+
+	// Description is a short summary of the site
+	Description string
+
+	// Details is optional markdown added after the description
+	Details string
+
+	// BaseURL is the base URL for the site (e.g., "https://example.com").
+	// Links are relative to the site if empty.
+	BaseURL string
+
+	// OutputPath is the path to write llms.txt (default: "llms.txt")
+	OutputPath string
+
+	// ExcludePatterns are glob patterns (relative to OutputDir) for pages
+	// that should not be listed
+	ExcludePatterns []string
+
+	// IncludeDrafts lists pages marked as drafts
+	IncludeDrafts bool
+
+	// SkipMarkdown disables writing the index.md renditions of pages
+	SkipMarkdown bool
+
+	// collected pages during build
+	pages []llmsPage
+}
+
+type llmsPage struct {
+	Title       string
+	Description string
+	URL         string
+	Section     string
+
+	// Markdown is the rendition of the page, if it has one
+	Markdown string
+}
+
+// Phase returns PhaseFinalize - llms.txt is written after all content is generated.
+func (g *LLMsTxtGenerator) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns patterns for HTML files.
+func (g *LLMsTxtGenerator) DependsOn() []string {
+	return []string{"**/*.html"}
+}
+
+// Produces returns llms.txt and the markdown renditions of pages.
+func (g *LLMsTxtGenerator) Produces() []string {
+	return []string{"llms.txt", "**/index.md"}
+}
+
+// TargetsFor returns nil - LLMsTxtGenerator uses hooks instead of per-resource targets.
+func (g *LLMsTxtGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (g *LLMsTxtGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the llms.txt generator to a site.
+func (g *LLMsTxtGenerator) Register(site *Site) {
+	// Set defaults
+	if g.OutputPath == "" {
+		g.OutputPath = "llms.txt"
+	}
+
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	// Reset pages at start of build
+	site.Hooks.OnPhaseStart(PhaseDiscover, func(ctx *BuildContext) {
+		g.pages = nil
+	})
+
+	// Collect pages as resources are processed
+	site.Hooks.OnResourceProcessed(func(ctx *BuildContext, res *Resource, targets []*Resource) {
+		for _, target := range targets {
+			if page, ok := g.collectPage(ctx.Site, res, target); ok {
+				if err := g.writeMarkdown(target, page); err != nil {
+					ctx.AddError(fmt.Errorf("markdown rendition of %s failed: %w", res.FullPath, err))
+					continue
+				}
+				g.pages = append(g.pages, page)
+			}
+		}
+	})
+
+	// Write llms.txt at end of Finalize phase
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		if err := g.writeLLMsTxt(ctx.Site.OutputDir); err != nil {
+			ctx.AddError(fmt.Errorf("llms.txt generation failed: %w", err))
+		}
+	})
+}
+
+func (g *LLMsTxtGenerator) collectPage(site *Site, res *Resource, target *Resource) (page llmsPage, ok bool) {
+	if res == nil || filepath.Base(target.FullPath) != "index.html" || isLaterPage(target) {
+		return
+	}
+	relPath, err := filepath.Rel(site.OutputDir, target.FullPath)
+	if err != nil {
+		return
+	}
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range g.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, relPath); matched {
+			return
+		}
+	}
+
+	fm := res.FrontMatter().Data
+	if draft, _ := fm["draft"].(bool); draft && !g.IncludeDrafts {
+		return
+	}
+	page.Title, _ = fm["title"].(string)
+	if page.Title == "" {
+		return // Skip pages without title
+	}
+	page.Description, _ = fm["description"].(string)
+	if page.Description == "" {
+		page.Description, _ = fm["summary"].(string)
+	}
+	page.URL = strings.TrimSuffix(g.BaseURL, "/") + site.TargetURL(target)
+	if contentPath, err := filepath.Rel(site.ContentRoot, res.FullPath); err == nil {
+		if dir := filepath.ToSlash(filepath.Dir(contentPath)); dir != "." {
+			page.Section, _, _ = strings.Cut(dir, "/")
+		}
+	}
+
+	// Parametric pages share their source so only the page is listed
+	if md, ok := res.Document.Metadata[markdownContentKey].([]byte); ok && !g.SkipMarkdown && !isParametricPath(res.FullPath) {
+		page.Markdown = markdownRendition(page, rewriteMarkdownLinks(res, md))
+	}
+	return page, true
+}
+
+// markdownRendition returns the markdown of a page with its title and
+// description as the heading.
+func markdownRendition(page llmsPage, md []byte) string {
+	var b strings.Builder
+	md = bytes.TrimSpace(md)
+	if !bytes.HasPrefix(md, []byte("# ")) {
+		fmt.Fprintf(&b, "# %s\n\n", page.Title)
+	}
+	if page.Description != "" {
+		fmt.Fprintf(&b, "> %s\n\n", page.Description)
+	}
+	b.Write(md)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// rewriteMarkdownLinks rewrites the relative links to content files in the
+// markdown of a page to the URLs of the linked pages, as LinkRewriter does
+// for the HTML, since the rendition is served from another directory. Links
+// and images of co-located assets are rewritten to the asset URLs, eg their
+// fingerprinted names. Only the destinations of links found by the parser
+// are rewritten, so code is left as is.
+func rewriteMarkdownLinks(res *Resource, md []byte) []byte {
+	rewriter := &LinkRewriter{}
+	pc := parser.NewContext()
+	doc := goldmark.New().Parser().Parse(text.NewReader(md), parser.WithContext(pc))
+
+	// The AST has no positions for link destinations, so they are found in
+	// the source after the end of the last text before them.
+	type edit struct {
+		start, stop int
+		dest        string
+	}
+	var edits []edit
+	var code [][2]int
+	lastStop := 0
+	rewrite := func(destination string) string {
+		// Broken links were reported when the HTML was rendered
+		if dest, err := rewriter.resolve(res, destination); err == nil && dest != "" {
+			return dest
+		}
+		if dest := coLocatedAssetURL(res, destination); dest != "" && dest != destination {
+			return dest
+		}
+		return ""
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		var destination []byte
+		switch n := n.(type) {
+		case *ast.Text:
+			if entering {
+				lastStop = n.Segment.Stop
+			}
+		case *ast.RawHTML:
+			if entering && n.Segments.Len() > 0 {
+				lastStop = n.Segments.At(n.Segments.Len() - 1).Stop
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			if lines := n.Lines(); entering && lines.Len() > 0 {
+				code = append(code, [2]int{lines.At(0).Start, lines.At(lines.Len() - 1).Stop})
+				lastStop = lines.At(lines.Len() - 1).Stop
+			}
+		case *ast.Link:
+			destination = n.Destination
+		case *ast.Image:
+			destination = n.Destination
+		}
+		if entering || destination == nil {
+			return ast.WalkContinue, nil
+		}
+		// Inline links continue with "](" after the label, reference links
+		// are rewritten at their definitions below.
+		end := bytes.IndexByte(md[lastStop:], ']')
+		if end < 0 || lastStop+end+1 >= len(md) || md[lastStop+end+1] != '(' {
+			return ast.WalkContinue, nil
+		}
+		start := lastStop + end + 2
+		for start < len(md) && (md[start] == ' ' || md[start] == '\t' || md[start] == '\n') {
+			start++
+		}
+		if start < len(md) && md[start] == '<' {
+			start++
+		}
+		if !bytes.HasPrefix(md[start:], destination) {
+			// Escaped destinations are left as is
+			return ast.WalkContinue, nil
+		}
+		lastStop = start + len(destination)
+		if dest := rewrite(string(destination)); dest != "" {
+			edits = append(edits, edit{start, lastStop, dest})
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Link reference definitions are not kept in the AST
+	for _, m := range linkDefinitionPattern.FindAllSubmatchIndex(md, -1) {
+		start, stop := m[4], m[5]
+		if !slices.ContainsFunc(pc.References(), func(ref parser.Reference) bool {
+			return bytes.EqualFold(ref.Label(), md[m[2]:m[3]]) && bytes.Equal(ref.Destination(), md[start:stop])
+		}) || slices.ContainsFunc(code, func(r [2]int) bool { return start >= r[0] && start < r[1] }) {
+			continue
+		}
+		if dest := rewrite(string(md[start:stop])); dest != "" {
+			edits = append(edits, edit{start, stop, dest})
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out []byte
+	last := 0
+	for _, e := range edits {
+		out = append(append(out, md[last:e.start]...), e.dest...)
+		last = e.stop
+	}
+	return append(out, md[last:]...)
+}
+
+// linkDefinitionPattern matches link reference definitions, eg
+// [setup]: ../setup.md. Group 1 is the label and group 2 the destination.
+var linkDefinitionPattern = regexp.MustCompile(`(?m)^ {0,3}\[([^\]]+)\]:[ \t]*\n?[ \t]*<?([^\s>]+)`)
+
+// coLocatedAssetURL returns the URL of the co-located asset of res that
+// destination links to, or "" if it does not link to one.
+func coLocatedAssetURL(res *Resource, destination string) string {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return ""
+	}
+	name := strings.TrimPrefix(u.Path, "./")
+	if !slices.ContainsFunc(res.Assets, func(asset *Resource) bool {
+		return filepath.Base(asset.FullPath) == name
+	}) {
+		return ""
+	}
+	out := GetAssetURL(res.Site, res, name)
+	if u.RawQuery != "" {
+		out += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		out += "#" + u.Fragment
+	}
+	return out
+}
+
+// writeMarkdown writes the markdown rendition of a page next to its HTML.
+func (g *LLMsTxtGenerator) writeMarkdown(target *Resource, page llmsPage) error {
+	if page.Markdown == "" {
+		return nil
+	}
+	outPath := filepath.Join(filepath.Dir(target.FullPath), "index.md")
+	return os.WriteFile(outPath, []byte(page.Markdown), 0644)
+}
+
+func (g *LLMsTxtGenerator) writeLLMsTxt(outputDir string) error {
+	if len(g.pages) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", g.Title)
+	if g.Description != "" {
+		fmt.Fprintf(&b, "> %s\n\n", g.Description)
+	}
+	if g.Details != "" {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(g.Details))
+	}
+
+	// Group pages by section, with top level pages first
+	sections := map[string][]llmsPage{}
+	for _, page := range g.pages {
+		sections[page.Section] = append(sections[page.Section], page)
+	}
+	var names []string
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		heading := name
+		if heading == "" {
+			heading = "Pages"
+		}
+		fmt.Fprintf(&b, "## %s\n\n", heading)
+		pages := sections[name]
+		sort.Slice(pages, func(i, j int) bool {
+			return pages[i].URL < pages[j].URL
+		})
+		for _, page := range pages {
+			link := page.URL
+			if page.Markdown != "" {
+				link += "index.md"
+			}
+			fmt.Fprintf(&b, "- [%s](%s)", page.Title, link)
+			if page.Description != "" {
+				fmt.Fprintf(&b, ": %s", page.Description)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	outPath := filepath.Join(outputDir, g.OutputPath)
+	log.Printf("[LLMs] Writing %d pages to %s", len(g.pages), outPath)
+	return os.WriteFile(outPath, []byte(strings.TrimSuffix(b.String(), "\n")), 0644)
+}
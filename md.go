@@ -25,6 +25,17 @@ import (
 // by Applying the root template defined in c.md as is
 type MDToHtml struct {
 	BaseToHtmlRule
+
+	// ImageHook, LinkHook and HeadingHook optionally replace how images, links
+	// and headings in the markdown are rendered. See ResponsiveImage for a
+	// ready made image hook.
+	ImageHook   *RenderHook
+	LinkHook    *RenderHook
+	HeadingHook *RenderHook
+
+	// WikiLinks enables [[Page]] style links to other pages (see WikiLinks).
+	// Off by default as existing content may have literal [[...]] text.
+	WikiLinks bool
 }
 
 // Phase returns PhaseGenerate - markdown conversion happens in the generate phase.
@@ -82,6 +93,7 @@ func (m *MDToHtml) MD() (md goldmark.Markdown, tocTransformer *TOCTransformer) {
 					Value:    tocTransformer,
 					Priority: 100,
 				},
+				util.Prioritized(&LinkRewriter{}, 200),
 			),
 		),
 		goldmark.WithRendererOptions(
@@ -90,6 +102,9 @@ func (m *MDToHtml) MD() (md goldmark.Markdown, tocTransformer *TOCTransformer) {
 			html.WithUnsafe(),
 		),
 	)
+	if m.WikiLinks {
+		(&WikiLinks{}).Extend(md)
+	}
 	return
 }
 
@@ -126,18 +141,23 @@ func (m *MDToHtml) Run(site *Site, inputs []*Resource, targets []*Resource, func
 	defer outfile.Close()
 
 	finalmd, err := m.LoadResourceTemplate(site, inres)
+	inres.Document.SetMetadata(markdownContentKey, finalmd)
 
 	params := map[any]any{
 		"Site":        site,
 		"Res":         inres,
 		"FrontMatter": inres.FrontMatter().Data,
 		"Content":     finalmd,
+		"Paginator":   inres.Paginator,
+		"Item":        inres.Item,
+		"Params":      inres.Params,
 	}
 	if template.Params != nil {
 		maps.Copy(params, template.Params)
 	}
 
 	md, tocTransformer := m.MD()
+	m.addRenderHooks(md, site, inres)
 
 	if funcs == nil {
 		funcs = map[string]any{}
@@ -156,7 +176,7 @@ func (m *MDToHtml) Run(site *Site, inputs []*Resource, targets []*Resource, func
 			if len(content) != len(finalmd) {
 				panic("Content and MD len do not match")
 			}
-			doc := md.Parser().Parse(text.NewReader(content))
+			doc := md.Parser().Parse(text.NewReader(content), parser.WithContext(newParserContext(inres)))
 			return &(struct {
 				Doc *ast.Document
 				TOC []TOCNode
@@ -174,6 +194,7 @@ func (m *MDToHtml) Run(site *Site, inputs []*Resource, targets []*Resource, func
 			if err != nil {
 				panic(err)
 			}
+			inres.Document.SetMetadata(renderedContentKey, htmpl.HTML(b.String()))
 			return htmpl.HTML(b.String()), err
 		},
 	})
@@ -213,14 +234,13 @@ func (m *MDToHtml) LoadResourceTemplate(site *Site, r *Resource) ([]byte, error)
 		"Res":         r,
 		"Site":        r.Site,
 		"FrontMatter": r.FrontMatter().Data,
+		"Paginator":   r.Paginator,
+		"Item":        r.Item,
+		"Params":      r.Params,
 	}
 
-	// Include AssetURL function for co-located asset references in markdown
-	funcs := map[string]any{
-		"AssetURL": func(filename string) string {
-			return GetAssetURL(r.Site, r, filename)
-		},
-	}
+	// Include AssetURL and ImageSet functions for co-located asset references in markdown
+	funcs := resourceFuncs(r)
 
 	finalmd := bytes.NewBufferString("")
 	err = r.Site.Templates.RenderTextTemplate(finalmd, template, "", params, funcs)
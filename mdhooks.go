@@ -0,0 +1,300 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	htmpl "html/template"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// RenderHook customises how a markdown element is rendered by MDToHtml.
+// Either Func or Template must be set - Func takes precedence.
+type RenderHook struct {
+	// Func renders the element and returns its HTML.
+	Func func(ctx *RenderHookContext) (htmpl.HTML, error)
+
+	// Template is the name of a template file (in the site's template
+	// folders) that is rendered with the RenderHookContext as its data.
+	Template string
+
+	// Entry is the name of the template to use within Template.
+	Entry string
+}
+
+// RenderHookContext describes the markdown element being rendered by a
+// RenderHook.
+type RenderHookContext struct {
+	Site *Site
+
+	// Res is the resource whose content is being rendered.
+	Res *Resource
+
+	// Kind is "image", "link" or "heading".
+	Kind string
+
+	// Destination is the URL of a link or image as written in the markdown.
+	Destination string
+
+	// URL is the URL to use in the output. For co-located assets this is the
+	// asset's URL, otherwise it is the same as Destination.
+	URL string
+
+	// Title is the title of a link or image.
+	Title string
+
+	// Text is the plain text of the element, eg the alt text of an image.
+	Text string
+
+	// Content is the rendered HTML inside a link or heading.
+	Content htmpl.HTML
+
+	// Level and ID are the level (1-6) and id attribute of a heading.
+	Level int
+	ID    string
+
+	// Attributes holds any other attributes set on the element.
+	Attributes map[string]string
+
+	// Asset is the co-located asset a link or image refers to, if any.
+	Asset *Resource
+
+	// Image and Info are the responsive variants and metadata of a co-located
+	// image. Both are nil for other images.
+	Image *ImageSet
+	Info  *ImageInfo
+}
+
+func (h *RenderHook) render(ctx *RenderHookContext) (htmpl.HTML, error) {
+	if h.Func != nil {
+		return h.Func(ctx)
+	}
+	if h.Template == "" {
+		return "", fmt.Errorf("render hook for %s has neither a Func nor a Template", ctx.Kind)
+	}
+	tmpl, err := ctx.Site.Templates.Loader.Load(h.Template, "")
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	err = ctx.Site.Templates.RenderHtmlTemplate(&b, tmpl[0], h.Entry, ctx, resourceFuncs(ctx.Res))
+	return htmpl.HTML(b.String()), err
+}
+
+// ResponsiveImage is an image render hook that emits lazy loaded images.
+// Co-located images get their intrinsic dimensions and, if they were
+// processed by ImageTransform, a <picture> with a <source> of the resized
+// variants per format and the <img> as the fallback. Use it with:
+//
+//	&MDToHtml{ImageHook: &RenderHook{Func: ResponsiveImage}}
+func ResponsiveImage(ctx *RenderHookContext) (htmpl.HTML, error) {
+	esc := htmpl.HTMLEscapeString
+	width, height := 0, 0
+	if ctx.Info != nil {
+		width, height = ctx.Info.Width, ctx.Info.Height
+	} else if ctx.Image != nil {
+		width, height = ctx.Image.Width, ctx.Image.Height
+	}
+	sizes := ""
+	if width > 0 {
+		sizes = fmt.Sprintf(` sizes="(max-width: %dpx) 100vw, %dpx"`, width, width)
+	}
+
+	var b strings.Builder
+	picture := ctx.Image != nil && len(ctx.Image.Variants) > 0
+	if picture {
+		b.WriteString("<picture>")
+		for _, source := range ctx.Image.Sources() {
+			fmt.Fprintf(&b, `<source type="%s" srcset="%s"%s />`, esc(source.Type), esc(source.SrcSet), sizes)
+		}
+	}
+	fmt.Fprintf(&b, `<img src="%s" alt="%s"`, esc(ctx.URL), esc(ctx.Text))
+	if ctx.Title != "" {
+		fmt.Fprintf(&b, ` title="%s"`, esc(ctx.Title))
+	}
+	if width > 0 && height > 0 {
+		fmt.Fprintf(&b, ` width="%d" height="%d"`, width, height)
+	}
+	b.WriteString(` loading="lazy" decoding="async" />`)
+	if picture {
+		b.WriteString("</picture>")
+	}
+	return htmpl.HTML(b.String()), nil
+}
+
+// renderHooks is a goldmark node renderer that hands images, links and
+// headings to the render hooks configured on an MDToHtml rule.
+type renderHooks struct {
+	rule *MDToHtml
+	site *Site
+	res  *Resource
+	md   goldmark.Markdown
+}
+
+// addRenderHooks registers the rule's render hooks on md for rendering res.
+func (m *MDToHtml) addRenderHooks(md goldmark.Markdown, site *Site, res *Resource) {
+	if m.ImageHook == nil && m.LinkHook == nil && m.HeadingHook == nil {
+		return
+	}
+	hooks := &renderHooks{rule: m, site: site, res: res, md: md}
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(hooks, 100)))
+}
+
+func (r *renderHooks) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	if r.rule.ImageHook != nil {
+		reg.Register(ast.KindImage, r.renderImage)
+	}
+	if r.rule.LinkHook != nil {
+		reg.Register(ast.KindLink, r.renderLink)
+	}
+	if r.rule.HeadingHook != nil {
+		reg.Register(ast.KindHeading, r.renderHeading)
+	}
+}
+
+func (r *renderHooks) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	ctx := r.newContext("image", node, string(n.Destination))
+	ctx.Title = string(n.Title)
+	ctx.Text = plainText(n, source)
+	if ctx.Asset != nil && isImagePath(ctx.Asset.FullPath) {
+		ctx.Image, _ = GetImageSet(r.site, r.res, filepath.Base(ctx.Asset.FullPath))
+		ctx.Info = ImageInfoOf(ctx.Asset)
+	}
+	return r.write(w, r.rule.ImageHook, ctx)
+}
+
+func (r *renderHooks) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Link)
+	ctx := r.newContext("link", node, string(n.Destination))
+	ctx.Title = string(n.Title)
+	ctx.Text = plainText(n, source)
+	content, err := r.renderChildren(source, n)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	ctx.Content = content
+	return r.write(w, r.rule.LinkHook, ctx)
+}
+
+func (r *renderHooks) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Heading)
+	ctx := r.newContext("heading", node, "")
+	ctx.Level = n.Level
+	ctx.Text = plainText(n, source)
+	if id, ok := n.AttributeString("id"); ok {
+		ctx.ID = attributeValue(id)
+		delete(ctx.Attributes, "id")
+	}
+	content, err := r.renderChildren(source, n)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	ctx.Content = content
+	return r.write(w, r.rule.HeadingHook, ctx)
+}
+
+func (r *renderHooks) newContext(kind string, node ast.Node, destination string) *RenderHookContext {
+	ctx := &RenderHookContext{
+		Site:        r.site,
+		Res:         r.res,
+		Kind:        kind,
+		Destination: destination,
+		URL:         destination,
+		Attributes:  map[string]string{},
+	}
+	for _, attr := range node.Attributes() {
+		ctx.Attributes[string(attr.Name)] = attributeValue(attr.Value)
+	}
+	if asset := findAsset(r.res, destination); asset != nil {
+		ctx.Asset = asset
+		ctx.URL = GetAssetURL(r.site, r.res, filepath.Base(asset.FullPath))
+	}
+	return ctx
+}
+
+func (r *renderHooks) write(w util.BufWriter, hook *RenderHook, ctx *RenderHookContext) (ast.WalkStatus, error) {
+	out, err := hook.render(ctx)
+	if err != nil {
+		return ast.WalkStop, fmt.Errorf("%s render hook failed in %s: %w", ctx.Kind, r.res.FullPath, err)
+	}
+	w.WriteString(string(out))
+	return ast.WalkSkipChildren, nil
+}
+
+// renderChildren renders the children of a node with the full renderer so
+// nested markup (and other hooks) are applied.
+func (r *renderHooks) renderChildren(source []byte, n ast.Node) (htmpl.HTML, error) {
+	var b bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if err := r.md.Renderer().Render(&b, source, c); err != nil {
+			return "", err
+		}
+	}
+	return htmpl.HTML(b.String()), nil
+}
+
+// findAsset returns the co-located asset of res that a relative URL refers to.
+func findAsset(res *Resource, destination string) *Resource {
+	if destination == "" || strings.HasPrefix(destination, "/") || strings.HasPrefix(destination, "#") {
+		return nil
+	}
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return nil
+	}
+	fullpath := filepath.Join(filepath.Dir(res.FullPath), filepath.FromSlash(u.Path))
+	for _, asset := range res.Assets {
+		if asset.FullPath == fullpath {
+			return asset
+		}
+	}
+	return nil
+}
+
+// plainText returns the text content of a node and its descendants.
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+func attributeValue(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
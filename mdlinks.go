@@ -0,0 +1,120 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// mdResourceKey holds the resource being parsed in the goldmark parser context.
+var mdResourceKey = parser.NewContextKey()
+
+// newParserContext returns a goldmark parser context for parsing res.
+func newParserContext(res *Resource) parser.Context {
+	pc := parser.NewContext()
+	pc.Set(mdResourceKey, res)
+	return pc
+}
+
+// LinkRewriter is a goldmark AST transformer that rewrites relative links to
+// content files (eg [setup](../setup.md)) to the URL the linked file is
+// rendered at, so links that work on GitHub also work on the site. Links to
+// content files that do not exist, or that cannot be rewritten as the file
+// is not rendered as a single page, are reported as build errors, once per
+// page even if the page is parsed several times. The links of the page,
+// including those in raw HTML, are collected for the link graph.
+type LinkRewriter struct{}
+
+func (t *LinkRewriter) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	res, _ := pc.Get(mdResourceKey).(*Resource)
+	if res == nil || res.Site == nil {
+		return
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			dest, err := t.resolve(res, string(n.Destination))
+			if err != nil {
+				res.Site.addBrokenLink(res, string(n.Destination), err)
+			} else if dest != "" {
+				n.Destination = []byte(dest)
+			}
+			res.Site.collectLinks(res, string(n.Destination))
+		case *ast.RawHTML:
+			res.Site.collectLinks(res, htmlLinks(n.Segments.Value(reader.Source()))...)
+		case *ast.HTMLBlock:
+			var b bytes.Buffer
+			for i := 0; i < n.Lines().Len(); i++ {
+				line := n.Lines().At(i)
+				b.Write(line.Value(reader.Source()))
+			}
+			res.Site.collectLinks(res, htmlLinks(b.Bytes())...)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// resolve returns the output URL for a link destination relative to res.
+// Returns "" if the destination is not a relative link to a content file.
+func (t *LinkRewriter) resolve(res *Resource, destination string) (string, error) {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || u.Path[0] == '/' {
+		return "", nil
+	}
+	if !slices.Contains(contentExtensions, filepath.Ext(u.Path)) {
+		return "", nil
+	}
+
+	site := res.Site
+	fullpath := filepath.Join(filepath.Dir(res.FullPath), filepath.FromSlash(u.Path))
+	if _, err := os.Stat(fullpath); err != nil {
+		return "", fmt.Errorf("broken link in %s: %s does not exist", res.FullPath, destination)
+	}
+
+	// Pages and their permalinks are indexed in the Discover phase, so the
+	// linked page need not have been rendered yet
+	if isParametricPath(fullpath) {
+		return "", fmt.Errorf("cannot rewrite link in %s: %s renders several pages", res.FullPath, destination)
+	}
+	target := site.resources[fullpath]
+	if target == nil || !slices.Contains(site.pages, target) {
+		return "", fmt.Errorf("cannot rewrite link in %s: %s is not rendered as a page", res.FullPath, destination)
+	}
+	out := site.resourceLink(target)
+	if u.RawQuery != "" {
+		out += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		out += "#" + u.Fragment
+	}
+	return out, nil
+}
+
+// addBrokenLink reports a broken link in a page unless it was already
+// reported in this build.
+func (s *Site) addBrokenLink(from *Resource, destination string, err error) {
+	if slices.Contains(s.brokenLinks[from.FullPath], destination) {
+		return
+	}
+	if s.brokenLinks == nil {
+		s.brokenLinks = map[string][]string{}
+	}
+	s.brokenLinks[from.FullPath] = append(s.brokenLinks[from.FullPath], destination)
+
+	if s.buildCtx != nil {
+		s.buildCtx.AddError(err)
+	} else {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,212 @@
+package s3gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// MenuEntry is an entry of a named menu in Site.Menus. Pages can also add
+// themselves to menus with the menu front matter field, which takes the
+// same fields (in lower case):
+//
+//	menu: main             # or a list of menus
+//
+//	menu:
+//	  main:
+//	    weight: 10
+//	    title: Docs
+//	    parent: guides
+type MenuEntry struct {
+	// Name identifies the entry so other entries can be nested under it
+	// (default: the slug of the title)
+	Name string
+
+	// Title is the text of the entry (default: the title of the page)
+	Title string
+
+	// URL is the link of the entry
+	URL string
+
+	// Page is a reference to the page the entry links to, instead of URL
+	// (see Site.ResolveRef)
+	Page string
+
+	// Weight orders the entries, lightest first, then by title
+	Weight int
+
+	// Parent is the name of the entry this entry is nested under
+	Parent string
+
+	// Children are entries nested under this entry
+	Children []MenuEntry
+}
+
+// MenuItem is an entry of a menu returned by the Menu template function,
+// with its state for the page being rendered.
+type MenuItem struct {
+	Name   string
+	Title  string
+	URL    string
+	Weight int
+
+	// Page is the page the entry links to, if any
+	Page *Resource
+
+	// Active is true if the entry links to the page being rendered
+	Active bool
+
+	// ActiveTrail is true if the entry or one of its descendants is active,
+	// eg to expand it
+	ActiveTrail bool
+
+	// Children are the nested entries, ordered by weight, then title
+	Children []*MenuItem
+}
+
+// menuEntry is an entry of a menu with its page resolved.
+type menuEntry struct {
+	MenuEntry
+	page *Resource
+}
+
+// buildMenus collects the entries of each menu from the site configuration
+// and the front matter of pages.
+func (s *Site) buildMenus(ctx *BuildContext) {
+	s.menus = map[string][]menuEntry{}
+	for menu, entries := range s.Menus {
+		s.addMenuEntries(ctx, menu, entries, "")
+	}
+
+	for _, res := range s.pages {
+		base := res.Base.(*DefaultResourceBase)
+		if base.IsDraft {
+			continue
+		}
+		for menu, val := range pageMenus(res.FrontMatter().Data["menu"]) {
+			entry := menuEntry{page: res}
+			entry.Title = base.Title
+			entry.URL = base.Link
+			if v, ok := frontMatterValue(val, "title"); ok {
+				entry.Title = fmt.Sprint(v)
+			}
+			if v, ok := frontMatterValue(val, "name"); ok {
+				entry.Name = fmt.Sprint(v)
+			}
+			if v, ok := frontMatterValue(val, "parent"); ok {
+				entry.Parent = fmt.Sprint(v)
+			}
+			if v, ok := frontMatterValue(val, "weight"); ok {
+				entry.Weight = gotl.ToInt(v)
+			}
+			if entry.Name == "" {
+				entry.Name = gotl.Slugify(entry.Title)
+			}
+			s.menus[menu] = append(s.menus[menu], entry)
+		}
+	}
+}
+
+// addMenuEntries adds configured entries (and their children) to a menu.
+func (s *Site) addMenuEntries(ctx *BuildContext, menu string, entries []MenuEntry, parent string) {
+	for _, e := range entries {
+		entry := menuEntry{MenuEntry: e}
+		entry.Children = nil
+		if entry.Parent == "" {
+			entry.Parent = parent
+		}
+		if e.Page != "" {
+			entry.page = s.ResolveRef(nil, e.Page)
+			if entry.page == nil {
+				ctx.AddError(fmt.Errorf("%s menu entry %q: page %s not found", menu, e.Title, e.Page))
+				continue
+			}
+			base := s.resourceBase(entry.page)
+			entry.URL = base.Link
+			if entry.Title == "" {
+				entry.Title = base.Title
+			}
+		}
+		if entry.Name == "" {
+			entry.Name = gotl.Slugify(entry.Title)
+		}
+		s.menus[menu] = append(s.menus[menu], entry)
+		s.addMenuEntries(ctx, menu, e.Children, entry.Name)
+	}
+}
+
+// pageMenus returns the menus a page is in, from the menu front matter,
+// mapped to the page's settings in each menu.
+func pageMenus(val any) map[string]any {
+	out := map[string]any{}
+	switch menus := val.(type) {
+	case string:
+		out[menus] = nil
+	case []any:
+		for _, menu := range menus {
+			out[fmt.Sprint(menu)] = nil
+		}
+	case map[string]any:
+		for menu, settings := range menus {
+			out[menu] = settings
+		}
+	case map[any]any:
+		for menu, settings := range menus {
+			out[fmt.Sprint(menu)] = settings
+		}
+	}
+	return out
+}
+
+// Menu returns the entries of a named menu as a tree, with the entries
+// linking to res (the page being rendered) and their ancestors marked as
+// active.
+func (s *Site) Menu(name string, res *Resource) (out []*MenuItem) {
+	entries := s.menus[name]
+	items := map[string]*MenuItem{}
+	var ordered []*MenuItem
+	for _, e := range entries {
+		item := &MenuItem{Name: e.Name, Title: e.Title, URL: e.URL, Weight: e.Weight, Page: e.page}
+		if res != nil {
+			if item.Page != nil {
+				item.Active = item.Page == res
+			} else if item.URL != "" {
+				item.Active = strings.TrimSuffix(item.URL, "/") == strings.TrimSuffix(s.resourceLink(res), "/")
+			}
+		}
+		if _, ok := items[e.Name]; !ok {
+			items[e.Name] = item
+		}
+		ordered = append(ordered, item)
+	}
+
+	for i, item := range ordered {
+		parent := items[entries[i].Parent]
+		if parent != nil && parent != item && entries[i].Parent != "" {
+			parent.Children = append(parent.Children, item)
+		} else {
+			// Entries with unknown parents are shown at the top level
+			out = append(out, item)
+		}
+	}
+	sortMenuItems(out)
+	return
+}
+
+// sortMenuItems orders a level of a menu and marks the active trail. Returns
+// true if any of the items is in the active trail.
+func sortMenuItems(items []*MenuItem) (active bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Weight != items[j].Weight {
+			return items[i].Weight < items[j].Weight
+		}
+		return items[i].Title < items[j].Title
+	})
+	for _, item := range items {
+		item.ActiveTrail = sortMenuItems(item.Children) || item.Active
+		active = active || item.ActiveTrail
+	}
+	return
+}
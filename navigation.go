@@ -0,0 +1,171 @@
+package s3gen
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// Series is a set of pages that are read in order, eg the parts of a
+// tutorial. Pages join a series with the series front matter field, either
+// as a name or with a weight to order the page within the series:
+//
+//	series: Go Basics
+//
+//	series:
+//	  name: Go Basics
+//	  weight: 2
+//
+// Pages are ordered by their series weight (or their weight field), then
+// by date.
+type Series struct {
+	// Name is the name of the series, as given by its first page
+	Name string
+
+	// Slug is the URL friendly name of the series
+	Slug string
+
+	// Pages are the pages of the series in reading order
+	Pages []*Resource
+}
+
+// Count returns the number of pages in the series.
+func (s *Series) Count() int {
+	return len(s.Pages)
+}
+
+// Position returns the position of a page in the series, starting at 1, or
+// 0 if the page is not part of the series.
+func (s *Series) Position(res *Resource) int {
+	return slices.Index(s.Pages, res) + 1
+}
+
+// Prev returns the page before a page in the series, or nil.
+func (s *Series) Prev(res *Resource) *Resource {
+	if i := slices.Index(s.Pages, res); i > 0 {
+		return s.Pages[i-1]
+	}
+	return nil
+}
+
+// Next returns the page after a page in the series, or nil.
+func (s *Series) Next(res *Resource) *Resource {
+	if i := slices.Index(s.Pages, res); i >= 0 && i+1 < len(s.Pages) {
+		return s.Pages[i+1]
+	}
+	return nil
+}
+
+// pageWeight returns the weight front matter field of a page (0 if not set).
+func pageWeight(res *Resource) int {
+	return gotl.ToInt(res.FrontMatter().Data["weight"])
+}
+
+// pageSeries returns the series name and weight of a page.
+func pageSeries(res *Resource) (name string, weight int) {
+	switch val := res.FrontMatter().Data["series"].(type) {
+	case string:
+		return val, pageWeight(res)
+	case nil:
+		return "", 0
+	default:
+		if v, ok := frontMatterValue(val, "name"); ok {
+			name, _ = v.(string)
+		}
+		weight = pageWeight(res)
+		if v, ok := frontMatterValue(val, "weight"); ok {
+			weight = gotl.ToInt(v)
+		}
+	}
+	return
+}
+
+// buildNavigation orders the pages of each series for the series
+// functions. Pages within sections are ordered by buildSections.
+func (s *Site) buildNavigation() {
+	s.series = map[string]*Series{}
+	seriesWeights := map[*Resource]int{}
+	for _, res := range s.pages {
+		if res.Base.(*DefaultResourceBase).IsDraft {
+			continue
+		}
+		if name, weight := pageSeries(res); strings.TrimSpace(name) != "" {
+			slug := gotl.Slugify(name)
+			if s.series[slug] == nil {
+				s.series[slug] = &Series{Name: strings.TrimSpace(name), Slug: slug}
+			}
+			s.series[slug].Pages = append(s.series[slug].Pages, res)
+			seriesWeights[res] = weight
+		}
+	}
+	for _, series := range s.series {
+		sortPages(series.Pages, func(res *Resource) int { return seriesWeights[res] })
+	}
+}
+
+// sortPages orders pages by weight, then date, then title.
+func sortPages(pages []*Resource, weight func(*Resource) int) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		r1, r2 := pages[i], pages[j]
+		if w1, w2 := weight(r1), weight(r2); w1 != w2 {
+			return w1 < w2
+		}
+		if d1, d2 := pageDate(r1), pageDate(r2); !d1.Equal(d2) {
+			return d1.Before(d2)
+		}
+		return r1.Base.(*DefaultResourceBase).Title < r2.Base.(*DefaultResourceBase).Title
+	})
+}
+
+// sectionSibling returns the page offset pages away from a page in its
+// section, or nil.
+func (s *Site) sectionSibling(res *Resource, offset int) *Resource {
+	if res == nil {
+		return nil
+	}
+	section := s.sections[s.pageSectionDir(res)]
+	if section == nil {
+		return nil
+	}
+	pages := section.Pages
+	i := slices.Index(pages, res)
+	if i < 0 || i+offset < 0 || i+offset >= len(pages) {
+		return nil
+	}
+	return pages[i+offset]
+}
+
+// PrevInSection returns the page before a page in its section (ordered by
+// weight, then date, then title), or nil if it is the first.
+func (s *Site) PrevInSection(res *Resource) *Resource {
+	return s.sectionSibling(res, -1)
+}
+
+// NextInSection returns the page after a page in its section, or nil if it
+// is the last.
+func (s *Site) NextInSection(res *Resource) *Resource {
+	return s.sectionSibling(res, 1)
+}
+
+// SeriesOf returns the series a page is part of, or nil.
+func (s *Site) SeriesOf(res *Resource) *Series {
+	if res == nil {
+		return nil
+	}
+	name, _ := pageSeries(res)
+	if series := s.series[gotl.Slugify(name)]; series != nil && series.Position(res) > 0 {
+		return series
+	}
+	return nil
+}
+
+// PositionInSeries returns the position of a page in its series, starting
+// at 1, or 0 if the page is not part of a series.
+func (s *Site) PositionInSeries(res *Resource) int {
+	if series := s.SeriesOf(res); series != nil {
+		return series.Position(res)
+	}
+	return 0
+}
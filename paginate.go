@@ -0,0 +1,253 @@
+package s3gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// defaultPageSize is the number of items on a page of a paginated list when
+// the list page does not specify one.
+const defaultPageSize = 10
+
+// Paginator is one page of a paginated list page. It is passed to the
+// templates of each page as .Paginator (and is nil for pages that are not
+// paginated).
+type Paginator struct {
+	// Items are the pages listed on this page
+	Items []*Resource
+
+	// PageNumber is the number of this page, starting at 1
+	PageNumber int
+
+	// PageSize is the maximum number of items on a page
+	PageSize int
+
+	// TotalItems is the number of items across all pages
+	TotalItems int
+
+	// TotalPages is the number of pages
+	TotalPages int
+
+	// PageURLs are the URLs of all pages, PageURLs[0] being the first page
+	PageURLs []string
+
+	// FirstURL, LastURL, PrevURL and NextURL are the URLs of the first, last,
+	// previous and next pages. PrevURL and NextURL are empty on the first and
+	// last pages.
+	FirstURL string
+	LastURL  string
+	PrevURL  string
+	NextURL  string
+}
+
+// HasPrev returns true if there is a page before this one.
+func (p *Paginator) HasPrev() bool {
+	return p.PageNumber > 1
+}
+
+// HasNext returns true if there is a page after this one.
+func (p *Paginator) HasNext() bool {
+	return p.PageNumber < p.TotalPages
+}
+
+// pagination is the paginate front matter of a list page:
+//
+//	paginate:
+//	  section: blog    # content dir of the items (default: the page's dir)
+//	  tag: go          # only list pages with this tag
+//	  size: 10         # items per page
+//	  orderby: -date   # date, -date, title or -title
+//	  drafts: false    # list drafts too
+//	  path: page       # later pages are at <path>/N/
+//
+// "paginate: 20" is a shorthand for paginating the page's own section.
+type pagination struct {
+	Section string
+	Tag     string
+	Size    int
+	OrderBy string
+	Drafts  bool
+	Path    string
+}
+
+// paginationOf returns the pagination of a list page and false if the page
+// is not paginated.
+func paginationOf(res *Resource) (p pagination, ok bool) {
+	// Only content pages have front matter, so other resources (eg css or
+	// images) are not read
+	if res.IsParametric || !slices.Contains(contentExtensions, res.Ext()) {
+		return
+	}
+	val, found := res.FrontMatter().Data["paginate"]
+	if !found || val == nil || val == false {
+		return
+	}
+
+	p = pagination{Size: defaultPageSize, OrderBy: "-date", Path: "page"}
+	if contentPath, err := filepath.Rel(res.Site.ContentRoot, res.FullPath); err == nil {
+		if dir := filepath.ToSlash(filepath.Dir(contentPath)); dir != "." {
+			p.Section = dir
+		}
+	}
+
+	switch val.(type) {
+	case bool:
+	case map[string]any, map[any]any:
+		if v, ok := frontMatterValue(val, "size"); ok {
+			p.Size = gotl.ToInt(v)
+		}
+		if v, ok := frontMatterValue(val, "section"); ok {
+			p.Section = strings.Trim(filepath.ToSlash(fmt.Sprint(v)), "/")
+		}
+		if v, ok := frontMatterValue(val, "tag"); ok {
+			p.Tag = fmt.Sprint(v)
+		}
+		if v, ok := frontMatterValue(val, "orderby"); ok {
+			p.OrderBy = fmt.Sprint(v)
+		}
+		if v, ok := frontMatterValue(val, "drafts"); ok {
+			p.Drafts, _ = v.(bool)
+		}
+		if v, ok := frontMatterValue(val, "path"); ok {
+			p.Path = strings.Trim(fmt.Sprint(v), "/")
+		}
+	default:
+		// The page size, which TOML decodes as an int64
+		p.Size = gotl.ToInt(val)
+	}
+	if p.Size <= 0 {
+		p.Size = defaultPageSize
+	}
+	return p, true
+}
+
+// isPaginated returns true if a resource is a paginated list page.
+func isPaginated(res *Resource) bool {
+	_, ok := paginationOf(res)
+	return ok
+}
+
+// isLaterPage returns true if a target is the second or later page of a
+// paginated list. These repeat the list page so generators that describe
+// pages (feeds, search, llms.txt) skip them.
+func isLaterPage(target *Resource) bool {
+	return target.Paginator != nil && target.Paginator.PageNumber > 1
+}
+
+// paginatedTargets returns a target for each page of a paginated list page.
+// The first page is the page's usual target and later pages are at
+// <path>/N/index.html under it.
+func (p *ParametricPages) paginatedTargets(s *Site, r *Resource, pg pagination, renderer Rule) (targets []*Resource) {
+	_, first := renderer.TargetsFor(s, r)
+	if len(first) != 1 {
+		return nil
+	}
+
+	items := p.paginationItems(s, r, pg)
+	totalPages := max(1, (len(items)+pg.Size-1)/pg.Size)
+	urls := make([]string, totalPages)
+	for n := 1; n <= totalPages; n++ {
+		destres := first[0]
+		if n > 1 {
+			destpath := filepath.Join(filepath.Dir(first[0].FullPath), pg.Path, strconv.Itoa(n), "index.html")
+			destres = s.GetResource(destpath)
+			destres.Source = r
+			destres.Base = r.Base
+			destres.frontMatter = r.frontMatter
+		}
+		destres.ParamName = strconv.Itoa(n)
+		destres.Paginator = &Paginator{
+			Items:      items[min(len(items), (n-1)*pg.Size):min(len(items), n*pg.Size)],
+			PageNumber: n,
+			PageSize:   pg.Size,
+			TotalItems: len(items),
+			TotalPages: totalPages,
+		}
+		urls[n-1] = s.TargetURL(destres)
+		targets = append(targets, destres)
+	}
+
+	for _, t := range targets {
+		pager := t.Paginator
+		pager.PageURLs = urls
+		pager.FirstURL = urls[0]
+		pager.LastURL = urls[totalPages-1]
+		if pager.HasPrev() {
+			pager.PrevURL = urls[pager.PageNumber-2]
+		}
+		if pager.HasNext() {
+			pager.NextURL = urls[pager.PageNumber]
+		}
+	}
+	return
+}
+
+// paginationItems returns the pages listed by a paginated list page. These
+// are the pages under its section, including page bundles (index files in
+// sub directories) but not _index files or other list pages. Pages that have
+// not been processed yet are loaded so the list does not depend on the order
+// pages are built in.
+func (p *ParametricPages) paginationItems(s *Site, r *Resource, pg pagination) []*Resource {
+	sectionDir := filepath.Join(s.ContentRoot, filepath.FromSlash(pg.Section))
+	items := s.ListResources(
+		func(res *Resource) bool {
+			if res == r || res.AssetOf != nil || isParametricPath(res.FullPath) {
+				return false
+			}
+			if _, ok := p.Renderers[res.Ext()]; !ok {
+				return false
+			}
+			rel, err := filepath.Rel(sectionDir, res.FullPath)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return false
+			}
+			if isIndexPath(res.FullPath) && (strings.HasPrefix(filepath.Base(rel), "_index.") || filepath.Dir(rel) == ".") {
+				return false
+			}
+			base := s.resourceBase(res)
+			if base == nil || (base.IsDraft && !pg.Drafts) || isPaginated(res) {
+				return false
+			}
+			if pg.Tag == "" {
+				return true
+			}
+			for _, t := range base.Tags {
+				if t == pg.Tag || gotl.Slugify(t) == gotl.Slugify(pg.Tag) {
+					return true
+				}
+			}
+			return false
+		},
+		nil, -1, -1)
+
+	orderby := pg.OrderBy
+	desc := strings.HasPrefix(orderby, "-")
+	orderby = strings.TrimPrefix(orderby, "-")
+	sort.SliceStable(items, func(i, j int) bool {
+		r1, r2 := items[i], items[j]
+		if desc {
+			r1, r2 = r2, r1
+		}
+		if orderby == "title" {
+			return r1.Base.(*DefaultResourceBase).Title < r2.Base.(*DefaultResourceBase).Title
+		}
+		return pageDate(r1).Before(pageDate(r2))
+	})
+	return items
+}
+
+// pageDate returns the date of a page from its front matter, falling back to
+// its creation time.
+func pageDate(res *Resource) time.Time {
+	if t, ok := parseDate(res.FrontMatter().Data["date"]); ok {
+		return t
+	}
+	return res.Base.(*DefaultResourceBase).CreatedAt
+}
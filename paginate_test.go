@@ -0,0 +1,82 @@
+package s3gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaginationOf(t *testing.T) {
+	tests := []struct {
+		name, path, content string
+		want                pagination
+		ok                  bool
+	}{{
+		name:    "size shorthand in YAML",
+		path:    "blog/_index.md",
+		content: "---\npaginate: 5\n---\n",
+		want:    pagination{Section: "blog", Size: 5, OrderBy: "-date", Path: "page"},
+		ok:      true,
+	}, {
+		name:    "size shorthand in TOML",
+		path:    "blog/_index.md",
+		content: "+++\npaginate = 5\n+++\n",
+		want:    pagination{Section: "blog", Size: 5, OrderBy: "-date", Path: "page"},
+		ok:      true,
+	}, {
+		name:    "true uses the defaults",
+		path:    "index.md",
+		content: "---\npaginate: true\n---\n",
+		want:    pagination{Size: defaultPageSize, OrderBy: "-date", Path: "page"},
+		ok:      true,
+	}, {
+		name:    "map in YAML",
+		path:    "archive.md",
+		content: "---\npaginate:\n  section: /posts/go/\n  tag: go\n  size: \"3\"\n  orderby: title\n  drafts: true\n  path: /p/\n---\n",
+		want:    pagination{Section: "posts/go", Tag: "go", Size: 3, OrderBy: "title", Drafts: true, Path: "p"},
+		ok:      true,
+	}, {
+		name:    "table in TOML",
+		path:    "archive.md",
+		content: "+++\n[paginate]\nsection = \"posts\"\nsize = 4\n+++\n",
+		want:    pagination{Section: "posts", Size: 4, OrderBy: "-date", Path: "page"},
+		ok:      true,
+	}, {
+		name:    "invalid size uses the default",
+		path:    "blog/_index.md",
+		content: "---\npaginate:\n  size: 0\n---\n",
+		want:    pagination{Section: "blog", Size: defaultPageSize, OrderBy: "-date", Path: "page"},
+		ok:      true,
+	}, {
+		name:    "false",
+		path:    "blog/_index.md",
+		content: "---\npaginate: false\n---\n",
+	}, {
+		name:    "no paginate",
+		path:    "blog/_index.md",
+		content: "---\ntitle: Blog\n---\n",
+	}, {
+		name:    "not a content page",
+		path:    "css/site.css",
+		content: "---\npaginate: 5\n---\n",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			fullpath := filepath.Join(root, filepath.FromSlash(test.path))
+			if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(fullpath, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			res := &Resource{Site: &Site{ContentRoot: root}, FullPath: fullpath}
+
+			got, ok := paginationOf(res)
+			if ok != test.ok || got != test.want {
+				t.Errorf("paginationOf() = %+v, %v, want %+v, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
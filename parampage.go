@@ -7,6 +7,8 @@ import (
 	"log"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	gotl "github.com/panyam/goutils/template"
 	"github.com/panyam/templar"
@@ -15,7 +17,9 @@ import (
 // ParametricPages is a rule that handles the discovery and generation of
 // pages from a single parametric template (e.g., content/tags/[tag].html).
 // It acts as a dispatcher, delegating the final rendering to a sub-rule
-// based on the file extension.
+// based on the file extension. Paginated list pages (with paginate front
+// matter) are rendered once per page in the same way, and parametric pages
+// with items front matter once per record of a data file.
 type ParametricPages struct {
 	// Renderers is a map of file extensions to the Rule that should be used
 	// for rendering that file type. For example:
@@ -57,13 +61,23 @@ func (p *ParametricPages) TargetsFor(s *Site, r *Resource) (siblings []*Resource
 		loader.LoadResource(s, r)
 	}
 
+	// Paginated list pages get a target for each page of items.
+	if pg, ok := paginationOf(r); ok {
+		return []*Resource{r}, p.paginatedTargets(s, r, pg, renderer)
+	}
+
 	// This rule only applies to parametric resources.
 	if !r.IsParametric {
 		return nil, nil
 	}
 
+	// Pages generated from data records need no discovery.
+	if items, ok := dataItemsOf(r); ok {
+		return []*Resource{r}, p.dataTargets(s, r, items)
+	}
+
 	// Phase 1: Discovery.
-	if len(r.ParamValues) == 0 {
+	if len(r.ParamValues) == 0 && len(r.ParamSets) == 0 {
 		slog.Info("Discovering params for", "resource", r.FullPath)
 		r.ParamName = ""
 
@@ -95,26 +109,99 @@ func (p *ParametricPages) TargetsFor(s *Site, r *Resource) (siblings []*Resource
 	}
 
 	// Phase 2: Target Generation.
-	respath, _ := filepath.Rel(s.ContentRoot, r.FullPath)
-	ext := filepath.Ext(respath)
-	rem := respath[:len(respath)-len(ext)]
-	dirname := filepath.Dir(rem)
-
+	names := paramNames(r.RelPath())
+	paramSets := r.ParamSets
 	for _, paramValue := range r.ParamValues {
-		// Ensure paramValue is URL-safe
-		safeParamValue := gotl.Slugify(paramValue)
-		destpath := filepath.Join(s.OutputDir, dirname, safeParamValue, "index.html")
-		destres := s.GetResource(destpath)
-		destres.Source = r
-		destres.Base = r.Base
-		destres.frontMatter = r.frontMatter
-		destres.ParamName = paramValue // Keep original param name for display
-		targets = append(targets, destres)
+		// Values of pages with several params are joined by "/"
+		values := []string{paramValue}
+		if len(names) > 1 {
+			values = strings.Split(paramValue, "/")
+		}
+		params := map[string]string{}
+		for i, name := range names {
+			if i < len(values) {
+				params[name] = values[i]
+			}
+		}
+		paramSets = append(paramSets, params)
+	}
+
+	seen := map[string]bool{}
+	for _, params := range paramSets {
+		destpath, err := paramTargetPath(s, r, params)
+		if err != nil {
+			s.addResourceError(r, err)
+		} else if !seen[destpath] {
+			seen[destpath] = true
+			targets = append(targets, p.paramTarget(s, r, destpath, params))
+		}
 	}
 
 	return []*Resource{r}, targets
 }
 
+// paramTargetPath returns the output path of a parametric page for the
+// values of its params. Each param in the path is replaced by its slugified
+// value, eg blog/[year]/[month]/[slug].md is written to
+// blog/2024/05/hello/index.html.
+func paramTargetPath(s *Site, r *Resource, params map[string]string) (string, error) {
+	respath, _ := filepath.Rel(s.ContentRoot, r.FullPath)
+	segments := strings.Split(filepath.ToSlash(respath), "/")
+	base := segments[len(segments)-1]
+	for ext := filepath.Ext(base); ext != ""; ext = filepath.Ext(base) {
+		base = base[:len(base)-len(ext)]
+	}
+	segments[len(segments)-1] = base
+
+	for i, segment := range segments {
+		if len(segment) <= 2 || segment[0] != '[' || segment[len(segment)-1] != ']' {
+			continue
+		}
+		name := segment[1 : len(segment)-1]
+		// Ensure the value is URL-safe
+		if segments[i] = gotl.Slugify(params[name]); segments[i] == "" {
+			return "", fmt.Errorf("no value for param %s", name)
+		}
+	}
+	return filepath.Join(append(append([]string{s.OutputDir}, segments...), "index.html")...), nil
+}
+
+// paramTarget returns the target of a parametric page at destpath for the
+// values of its params.
+func (p *ParametricPages) paramTarget(s *Site, r *Resource, destpath string, params map[string]string) *Resource {
+	var values []string
+	for _, name := range paramNames(r.RelPath()) {
+		values = append(values, params[name])
+	}
+	destres := s.GetResource(destpath)
+	destres.Source = r
+	destres.Base = r.Base
+	destres.frontMatter = r.frontMatter
+	destres.ParamName = strings.Join(values, "/") // Keep original values for display
+	destres.Params = params
+	return destres
+}
+
+// addResourceError reports an error with a resource to the build in
+// progress unless it was already reported in this build, eg as the targets
+// of the resource are computed several times.
+func (s *Site) addResourceError(r *Resource, err error) {
+	if slices.Contains(s.resourceErrors[r.FullPath], err.Error()) {
+		return
+	}
+	if s.resourceErrors == nil {
+		s.resourceErrors = map[string][]string{}
+	}
+	s.resourceErrors[r.FullPath] = append(s.resourceErrors[r.FullPath], err.Error())
+
+	err = fmt.Errorf("%s: %w", r.FullPath, err)
+	if s.buildCtx != nil {
+		s.buildCtx.AddError(err)
+	} else {
+		log.Println(err)
+	}
+}
+
 // Run finds the correct renderer based on the input file's extension
 // and delegates the rendering job to it.
 func (p *ParametricPages) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) (err error) {
@@ -129,8 +216,15 @@ func (p *ParametricPages) Run(site *Site, inputs []*Resource, targets []*Resourc
 		return fmt.Errorf("no renderer found for extension %s in ParametricPages rule", inres.Ext())
 	}
 
-	for idx, target := range targets {
-		outres := targets[0]
+	// The page state of a target is only valid while rendering it, so later
+	// renders and generators do not see the state of the last target.
+	defer func() {
+		inres.Paginator = nil
+		inres.Item = nil
+		inres.Params = nil
+	}()
+	for _, target := range targets {
+		outres := target
 		slog.Debug("Dispatching to renderer", "rule", fmt.Sprintf("%T", renderer), "in", inres.FullPath, "out", outres.FullPath, "param", outres.ParamName)
 
 		// Here's the delegation: call the Run method of the specialized rule.
@@ -145,7 +239,10 @@ func (p *ParametricPages) Run(site *Site, inputs []*Resource, targets []*Resourc
 
 		// For the purpose of this example, we will assume the existing Run methods
 		// can handle this. In a real implementation, you might need to adjust them.
-		inres.ParamName = inres.ParamValues[idx]
+		inres.ParamName = target.ParamName
+		inres.Paginator = target.Paginator
+		inres.Item = target.Item
+		inres.Params = target.Params
 		err2 := renderer.Run(site, inputs, []*Resource{target}, funcs)
 		err = errors.Join(err, err2)
 	}
@@ -50,6 +50,9 @@ type BuildContext struct {
 	// Errors accumulated during build (allows continuing on non-fatal errors)
 	Errors []error
 
+	// Warnings are problems reported during the build that do not fail it
+	Warnings []error
+
 	// Hooks for observation
 	hooks *HookRegistry
 }
@@ -61,6 +64,13 @@ func (ctx *BuildContext) AddError(err error) {
 	}
 }
 
+// AddWarning adds a warning to the build context.
+func (ctx *BuildContext) AddWarning(err error) {
+	if err != nil {
+		ctx.Warnings = append(ctx.Warnings, err)
+	}
+}
+
 // AddTarget adds a generated target to the context.
 func (ctx *BuildContext) AddTarget(target *Resource) {
 	ctx.GeneratedTargets = append(ctx.GeneratedTargets, target)
@@ -178,6 +188,18 @@ type AssetAwareRule interface {
 	HandleAssets(site *Site, res *Resource, assets []*Resource) ([]AssetMapping, error)
 }
 
+// AssetTransformRule is an optional interface for Transform phase rules that
+// process co-located assets (eg resizing images). Assets are normally skipped
+// by rules since they are copied along with their parent resource; rules
+// implementing this interface are offered assets as well.
+type AssetTransformRule interface {
+	PhaseRule
+
+	// TransformsAssets returns true if co-located assets should be offered
+	// to this rule.
+	TransformsAssets() bool
+}
+
 // LegacyRuleAdapter wraps a Rule that doesn't implement PhaseRule,
 // allowing it to work in the phase-based pipeline.
 type LegacyRuleAdapter struct {
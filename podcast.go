@@ -0,0 +1,193 @@
+package s3gen
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// Podcast turns the RSS feed of a FeedGenerator into a podcast feed. Only
+// pages with an audio file are included and the feed carries the iTunes
+// tags podcast directories expect. Episodes are described by these front
+// matter fields:
+//
+//	audio: episode.mp3   # co-located audio file, or a site path like /media/ep1.mp3
+//	duration: "42:17"    # HH:MM:SS, MM:SS or seconds
+//	episode: 12
+//	season: 2
+//	episodeType: full    # full, trailer or bonus
+//	explicit: false
+//	cover: cover.jpg     # episode art (co-located file or URL)
+type Podcast struct {
+	// Author is the name shown as the podcast's author
+	Author string
+
+	// OwnerName and OwnerEmail are the contact details of the owner
+	OwnerName  string
+	OwnerEmail string
+
+	// Image is the URL of the podcast's cover art, either absolute or a path
+	// on the site
+	Image string
+
+	// Categories are the iTunes categories, with subcategories after a "/",
+	// eg "Technology" or "Society & Culture/Documentary"
+	Categories []string
+
+	// Explicit marks the podcast as containing explicit content
+	Explicit bool
+
+	// Type is "episodic" (default) or "serial"
+	Type string
+
+	// Language is the language of the podcast (default: "en")
+	Language string
+
+	// Copyright is the copyright notice of the podcast
+	Copyright string
+}
+
+// podcastEpisode holds the iTunes fields of a feed item.
+type podcastEpisode struct {
+	Duration    string
+	Episode     string
+	Season      string
+	EpisodeType string
+	Explicit    *bool
+	Image       string
+}
+
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type itunesImageXML struct {
+	Href string `xml:"href,attr"`
+}
+
+type itunesOwnerXML struct {
+	Name  string `xml:"itunes:name,omitempty"`
+	Email string `xml:"itunes:email,omitempty"`
+}
+
+type itunesCategoryXML struct {
+	Text string              `xml:"text,attr"`
+	Sub  []itunesCategoryXML `xml:"itunes:category,omitempty"`
+}
+
+// itunesChannelXML holds the iTunes tags of a podcast channel.
+type itunesChannelXML struct {
+	Author     string              `xml:"itunes:author,omitempty"`
+	Owner      *itunesOwnerXML     `xml:"itunes:owner,omitempty"`
+	Image      *itunesImageXML     `xml:"itunes:image,omitempty"`
+	Categories []itunesCategoryXML `xml:"itunes:category,omitempty"`
+	Explicit   string              `xml:"itunes:explicit,omitempty"`
+	Type       string              `xml:"itunes:type,omitempty"`
+}
+
+// itunesItemXML holds the iTunes tags of a podcast episode.
+type itunesItemXML struct {
+	Title       string          `xml:"itunes:title,omitempty"`
+	Duration    string          `xml:"itunes:duration,omitempty"`
+	Episode     string          `xml:"itunes:episode,omitempty"`
+	Season      string          `xml:"itunes:season,omitempty"`
+	EpisodeType string          `xml:"itunes:episodeType,omitempty"`
+	Explicit    string          `xml:"itunes:explicit,omitempty"`
+	Image       *itunesImageXML `xml:"itunes:image,omitempty"`
+}
+
+// episode reads the podcast fields of a page. Returns false if the page has
+// no audio file. The audio file becomes the item's enclosure, and pages can
+// also use the enclosure field for audio hosted elsewhere.
+func (g *FeedGenerator) episode(site *Site, res *Resource, item *feedItem, fm map[string]any) bool {
+	if audio, _ := fm["audio"].(string); audio != "" {
+		if u, err := url.Parse(audio); err == nil && u.Scheme == "" && findAsset(res, audio) == nil && siteFile(site, audio) == "" {
+			err := fmt.Errorf("podcast episode %s: audio file %s is neither a co-located asset nor a file of the site", res.FullPath, audio)
+			if site.buildCtx != nil {
+				site.buildCtx.AddError(err)
+			} else {
+				log.Println(err)
+			}
+			return false
+		}
+		item.Enclosure = g.enclosure(site, res, item.Link, audio)
+	}
+	if item.Enclosure == nil {
+		return false
+	}
+
+	ep := &podcastEpisode{}
+	if v, ok := fm["duration"]; ok {
+		ep.Duration = fmt.Sprint(v)
+	}
+	if v, ok := fm["episode"]; ok {
+		ep.Episode = fmt.Sprint(v)
+	}
+	if v, ok := fm["season"]; ok {
+		ep.Season = fmt.Sprint(v)
+	}
+	if v, ok := fm["episodeType"].(string); ok {
+		ep.EpisodeType = v
+	}
+	if v, ok := fm["explicit"].(bool); ok {
+		ep.Explicit = &v
+	}
+	if v, ok := fm["cover"].(string); ok && v != "" {
+		ep.Image, _ = g.mediaURL(site, res, item.Link, v)
+	}
+	item.Episode = ep
+	return true
+}
+
+// itunesChannel returns the iTunes tags of the podcast channel.
+func (g *FeedGenerator) itunesChannel() *itunesChannelXML {
+	p := g.Podcast
+	out := &itunesChannelXML{
+		Author:   p.Author,
+		Explicit: fmt.Sprint(p.Explicit),
+		Type:     p.Type,
+	}
+	if out.Author == "" {
+		out.Author = g.Author
+	}
+	if out.Type == "" {
+		out.Type = "episodic"
+	}
+	if p.OwnerName != "" || p.OwnerEmail != "" {
+		out.Owner = &itunesOwnerXML{Name: p.OwnerName, Email: p.OwnerEmail}
+	}
+	if p.Image != "" {
+		image := p.Image
+		if strings.HasPrefix(image, "/") {
+			image = g.absURL(image)
+		}
+		out.Image = &itunesImageXML{Href: image}
+	}
+	for _, category := range p.Categories {
+		name, sub, hasSub := strings.Cut(category, "/")
+		c := itunesCategoryXML{Text: name}
+		if hasSub {
+			c.Sub = []itunesCategoryXML{{Text: sub}}
+		}
+		out.Categories = append(out.Categories, c)
+	}
+	return out
+}
+
+// itunesItem returns the iTunes tags of an episode.
+func itunesItem(item feedItem) *itunesItemXML {
+	ep := item.Episode
+	out := &itunesItemXML{
+		Title:       item.Title,
+		Duration:    ep.Duration,
+		Episode:     ep.Episode,
+		Season:      ep.Season,
+		EpisodeType: ep.EpisodeType,
+	}
+	if ep.Explicit != nil {
+		out.Explicit = fmt.Sprint(*ep.Explicit)
+	}
+	if ep.Image != "" {
+		out.Image = &itunesImageXML{Href: ep.Image}
+	}
+	return out
+}
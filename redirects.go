@@ -0,0 +1,318 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	htmpl "html/template"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Redirect is a URL path that redirects to a page, from the page's aliases
+// front matter.
+type Redirect struct {
+	// From is the path being redirected, relative to the site (without
+	// PathPrefix), eg "/old/post/"
+	From string
+
+	// To is the URL of the page, eg "/blog/new-post/"
+	To string
+
+	// Source is the full path of the page
+	Source string
+}
+
+// redirectPageTemplate is the page written at each alias. Browsers follow
+// the meta refresh and search engines the canonical link.
+var redirectPageTemplate = htmpl.Must(htmpl.New("redirect").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ . }}</title>
+<link rel="canonical" href="{{ . }}">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url={{ . }}">
+</head>
+<body>
+<p>This page has moved to <a href="{{ . }}">{{ . }}</a>.</p>
+</body>
+</html>
+`))
+
+// Redirects returns the redirects from the aliases of all pages, sorted by
+// the path being redirected.
+func (s *Site) Redirects() (out []Redirect) {
+	redirects := s.redirectMap()
+	for _, from := range slices.Sorted(maps.Keys(redirects)) {
+		out = append(out, redirects[from])
+	}
+	return
+}
+
+// redirectMap returns the redirects of the last build keyed by the path
+// being redirected. The map is replaced, never modified, by each build so
+// it can be read while the site is rebuilt.
+func (s *Site) redirectMap() map[string]Redirect {
+	if redirects := s.redirects.Load(); redirects != nil {
+		return *redirects
+	}
+	return nil
+}
+
+// aliasPath normalizes an alias to a site path. Aliases without an extension
+// are directories, eg "old/post" is served as "/old/post/".
+func aliasPath(alias string) string {
+	alias = path.Clean("/" + strings.TrimSpace(alias))
+	if alias != "/" && path.Ext(alias) == "" {
+		alias += "/"
+	}
+	return alias
+}
+
+// buildRedirects writes a redirect page at each alias of the pages generated
+// in this build and records the redirects for the redirect maps and for
+// serving the site. Redirect pages of aliases that were removed from these
+// pages are deleted.
+func (s *Site) buildRedirects(ctx *BuildContext) {
+	previous := s.redirectMap()
+	generated := map[string]bool{}
+	processed := map[string]bool{}
+	for _, t := range ctx.GeneratedTargets {
+		generated[t.FullPath] = true
+		if t.Source != nil {
+			processed[t.Source.FullPath] = true
+		}
+	}
+
+	// Redirects of the pages in this build are recreated below
+	redirects := map[string]Redirect{}
+	for from, r := range previous {
+		if !processed[r.Source] {
+			redirects[from] = r
+		}
+	}
+
+	for _, t := range ctx.GeneratedTargets {
+		if t.Source == nil || filepath.Ext(t.FullPath) != ".html" || isLaterPage(t) {
+			continue
+		}
+		res := t.Source
+
+		fm := res.FrontMatter().Data
+		aliases, _ := fm["aliases"].([]any)
+		if alias, ok := fm["aliases"].(string); ok {
+			aliases = []any{alias}
+		}
+		for _, a := range aliases {
+			alias, ok := a.(string)
+			if !ok || strings.TrimSpace(alias) == "" {
+				continue
+			}
+			from := aliasPath(alias)
+			to := s.TargetURL(t)
+
+			outpath := s.redirectPagePath(from)
+			if generated[outpath] {
+				ctx.AddError(fmt.Errorf("alias %s of %s conflicts with a generated page", alias, res.FullPath))
+				continue
+			}
+			if !isRedirectPage(outpath) {
+				ctx.AddError(fmt.Errorf("alias %s of %s conflicts with the existing page %s", alias, res.FullPath, outpath))
+				continue
+			}
+			if other, ok := redirects[from]; ok && other.Source != res.FullPath {
+				ctx.AddError(fmt.Errorf("alias %s of %s is also an alias of %s", alias, res.FullPath, other.Source))
+				continue
+			}
+
+			if err := writeRedirectPage(outpath, to); err != nil {
+				ctx.AddError(fmt.Errorf("redirect page for alias %s failed: %w", alias, err))
+				continue
+			}
+			redirects[from] = Redirect{From: from, To: to, Source: res.FullPath}
+		}
+	}
+
+	// Remove the redirect pages of aliases that are gone
+	for from := range previous {
+		if _, ok := redirects[from]; !ok {
+			if outpath := s.redirectPagePath(from); !generated[outpath] && isRedirectPage(outpath) {
+				os.Remove(outpath)
+			}
+		}
+	}
+
+	s.redirects.Store(&redirects)
+}
+
+// redirectPagePath returns the output path of the redirect page of an alias.
+func (s *Site) redirectPagePath(from string) string {
+	outpath := filepath.Join(s.OutputDir, filepath.FromSlash(from))
+	if strings.HasSuffix(from, "/") {
+		outpath = filepath.Join(outpath, "index.html")
+	}
+	return outpath
+}
+
+// isRedirectPage returns true if there is no file at a path or the file is
+// a redirect page, ie it can be (over)written with a redirect page without
+// losing a page of the site.
+func isRedirectPage(outpath string) bool {
+	data, err := os.ReadFile(outpath)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	var page bytes.Buffer
+	redirectPageTemplate.Execute(&page, "")
+	header, _, _ := bytes.Cut(page.Bytes(), []byte("<title>"))
+	return bytes.HasPrefix(data, header) && bytes.Contains(data, []byte(`<meta http-equiv="refresh"`))
+}
+
+func writeRedirectPage(outpath, to string) error {
+	if err := os.MkdirAll(filepath.Dir(outpath), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(outpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return redirectPageTemplate.Execute(f, to)
+}
+
+// redirectHandler redirects requests for aliases to their pages and passes
+// all other requests to next. Serve has already stripped the PathPrefix from
+// the request path.
+func (s *Site) redirectHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if redirect, ok := s.redirectMap()[aliasPath(r.URL.Path)]; ok {
+			http.Redirect(w, r, redirect.To, http.StatusMovedPermanently)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RedirectFormat is a redirect map format written by RedirectsGenerator.
+type RedirectFormat string
+
+const (
+	// RedirectsFile is a _redirects file as used by Netlify and Cloudflare
+	// Pages, written to RedirectsPath.
+	RedirectsFile RedirectFormat = "redirects"
+
+	// NginxMap is an nginx map of aliases to pages, written to NginxPath.
+	NginxMap RedirectFormat = "nginx"
+)
+
+// RedirectsGenerator writes the redirects from the aliases of pages (see
+// Site.Redirects) as redirect maps for static hosts in the Finalize phase.
+type RedirectsGenerator struct {
+	// Formats are the maps to write (default: all)
+	Formats []RedirectFormat
+
+	// RedirectsPath is the path to write the _redirects file (default: "_redirects")
+	RedirectsPath string
+
+	// NginxPath is the path to write the nginx map (default: "redirects.nginx.conf")
+	NginxPath string
+
+	// StatusCode is the HTTP status of the redirects (default: 301)
+	StatusCode int
+}
+
+// Phase returns PhaseFinalize - redirect maps are written after all content is generated.
+func (g *RedirectsGenerator) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns patterns for HTML files.
+func (g *RedirectsGenerator) DependsOn() []string {
+	return []string{"**/*.html"}
+}
+
+// Produces returns the redirect map files.
+func (g *RedirectsGenerator) Produces() []string {
+	return []string{"_redirects", "*.conf"}
+}
+
+// TargetsFor returns nil - RedirectsGenerator uses hooks instead of per-resource targets.
+func (g *RedirectsGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (g *RedirectsGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the redirects generator to a site.
+func (g *RedirectsGenerator) Register(site *Site) {
+	// Set defaults
+	if len(g.Formats) == 0 {
+		g.Formats = []RedirectFormat{RedirectsFile, NginxMap}
+	}
+	if g.RedirectsPath == "" {
+		g.RedirectsPath = "_redirects"
+	}
+	if g.NginxPath == "" {
+		g.NginxPath = "redirects.nginx.conf"
+	}
+	if g.StatusCode == 0 {
+		g.StatusCode = http.StatusMovedPermanently
+	}
+
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		redirects := ctx.Site.Redirects()
+		if len(redirects) == 0 {
+			return
+		}
+		for _, format := range g.Formats {
+			if err := g.writeMap(ctx.Site, format, redirects); err != nil {
+				ctx.AddError(fmt.Errorf("%s redirect map generation failed: %w", format, err))
+			}
+		}
+	})
+}
+
+func (g *RedirectsGenerator) writeMap(site *Site, format RedirectFormat, redirects []Redirect) error {
+	var b strings.Builder
+	var outPath string
+	switch format {
+	case RedirectsFile:
+		outPath = g.RedirectsPath
+		for _, r := range redirects {
+			fmt.Fprintf(&b, "%s %s %d\n", site.PathRelUrl(r.From), r.To, g.StatusCode)
+		}
+	case NginxMap:
+		outPath = g.NginxPath
+		b.WriteString("# Include in the http block and redirect with:\n")
+		fmt.Fprintf(&b, "#   if ($s3gen_redirect) { return %d $s3gen_redirect; }\n", g.StatusCode)
+		b.WriteString("map $uri $s3gen_redirect {\n")
+		for _, r := range redirects {
+			from := site.PathRelUrl(r.From)
+			fmt.Fprintf(&b, "    %s %s;\n", from, r.To)
+			if from != "/" && strings.HasSuffix(from, "/") {
+				fmt.Fprintf(&b, "    %s %s;\n", strings.TrimSuffix(from, "/"), r.To)
+			}
+		}
+		b.WriteString("}\n")
+	default:
+		return fmt.Errorf("unknown redirect format %q", format)
+	}
+
+	outPath = filepath.Join(site.OutputDir, outPath)
+	log.Printf("[Redirects] Writing %d redirects to %s", len(redirects), outPath)
+	return os.WriteFile(outPath, []byte(b.String()), 0644)
+}
@@ -0,0 +1,218 @@
+package s3gen
+
+import (
+	"bytes"
+	"fmt"
+	htmpl "html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	gotl "github.com/panyam/goutils/template"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// contentExtensions are the extensions of files that can be referenced as pages.
+var contentExtensions = []string{".md", ".mdx", ".html", ".htm"}
+
+// ResolveRef finds the page a reference points to. A reference can be a path
+// relative to the ContentRoot or to the referring page (with or without its
+// extension), or the slug, title or file name of a page. Returns nil if no
+// page matches.
+func (s *Site) ResolveRef(from *Resource, ref string) *Resource {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+
+	// Try as a path first
+	bases := []string{s.ContentRoot}
+	if from != nil {
+		bases = append([]string{filepath.Dir(from.FullPath)}, bases...)
+	}
+	for _, base := range bases {
+		p := filepath.Join(base, filepath.FromSlash(strings.TrimPrefix(ref, "/")))
+		candidates := []string{p}
+		for _, ext := range contentExtensions {
+			candidates = append(candidates, p+ext, filepath.Join(p, "index"+ext), filepath.Join(p, "_index"+ext))
+		}
+		for _, c := range candidates {
+			if !slices.Contains(contentExtensions, filepath.Ext(c)) {
+				continue
+			}
+			if info, err := os.Stat(c); err == nil && !info.IsDir() {
+				return s.GetResource(c)
+			}
+		}
+	}
+
+	// Then by slug, title or name
+	if s.refIndex == nil {
+		s.buildRefIndex()
+	}
+	if matches := s.refIndex[gotl.Slugify(ref)]; len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
+
+// buildRefIndex indexes all pages by the slugified forms of their title,
+// slug front matter and file name.
+func (s *Site) buildRefIndex() {
+	s.refIndex = map[string][]*Resource{}
+	pages := s.ListResources(func(res *Resource) bool {
+		return slices.Contains(contentExtensions, res.Ext()) && !isParametricPath(res.FullPath)
+	}, func(a, b *Resource) bool {
+		return a.FullPath < b.FullPath
+	}, 0, 0)
+
+	for _, res := range pages {
+		name := strings.TrimSuffix(filepath.Base(res.FullPath), filepath.Ext(res.FullPath))
+		if isIndexPath(res.FullPath) {
+			name = filepath.Base(filepath.Dir(res.FullPath))
+		}
+		keys := []string{name}
+		if fm := res.FrontMatter(); fm != nil && fm.Data != nil {
+			for _, field := range []string{"title", "slug"} {
+				if v, ok := fm.Data[field].(string); ok && v != "" {
+					keys = append(keys, v)
+				}
+			}
+		}
+		for _, key := range keys {
+			key = gotl.Slugify(key)
+			if !slices.Contains(s.refIndex[key], res) {
+				s.refIndex[key] = append(s.refIndex[key], res)
+			}
+		}
+	}
+}
+
+// resourceBase returns the base of a page, loading it if the page has not
+// been processed yet.
+func (s *Site) resourceBase(res *Resource) *DefaultResourceBase {
+	if res.Base == nil {
+		res.IsIndex = res.IsIndex || isIndexPath(res.FullPath)
+		res.IsParametric = isParametricPath(res.FullPath)
+		s.CreateResourceBase(res)
+	}
+	base, _ := res.Base.(*DefaultResourceBase)
+	return base
+}
+
+// resourceLink returns the permalink of a page, loading its base if the page
+// has not been processed yet.
+func (s *Site) resourceLink(res *Resource) string {
+	if base := s.resourceBase(res); base != nil {
+		return base.Link
+	}
+	return ""
+}
+
+// Ref returns the permalink of the page a reference points to (see
+// ResolveRef). Unresolved references are reported as build errors against
+// the referring page.
+func (s *Site) Ref(from *Resource, ref string) string {
+	target := s.ResolveRef(from, ref)
+	if target == nil {
+		s.addUnresolvedRef(from, ref)
+		return ""
+	}
+	return s.resourceLink(target)
+}
+
+// UnresolvedRefs returns the references (from Ref and wikilinks) that could
+// not be resolved in the last build, keyed by the full path of the page.
+func (s *Site) UnresolvedRefs() map[string][]string {
+	return s.unresolvedRefs
+}
+
+func (s *Site) addUnresolvedRef(from *Resource, ref string) {
+	page := ""
+	if from != nil {
+		page = from.FullPath
+	}
+	if slices.Contains(s.unresolvedRefs[page], ref) {
+		return
+	}
+	if s.unresolvedRefs == nil {
+		s.unresolvedRefs = map[string][]string{}
+	}
+	s.unresolvedRefs[page] = append(s.unresolvedRefs[page], ref)
+
+	err := fmt.Errorf("unresolved reference %q in %s", ref, page)
+	if s.buildCtx != nil {
+		s.buildCtx.AddError(err)
+	} else {
+		log.Println(err)
+	}
+}
+
+// WikiLinks is a goldmark extension for [[Page]], [[Page|label]] and
+// [[Page#heading]] style links. Targets are resolved with Site.ResolveRef
+// against the page being rendered. Enable it with MDToHtml.WikiLinks.
+type WikiLinks struct{}
+
+func (e *WikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(&wikiLinkParser{}, 199)))
+}
+
+type wikiLinkParser struct{}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	res, _ := pc.Get(mdResourceKey).(*Resource)
+	line, _ := block.PeekLine()
+	if res == nil || res.Site == nil || len(line) < 5 || line[1] != '[' {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end <= 0 {
+		return nil
+	}
+	inner := string(line[2 : 2+end])
+	if strings.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+	block.Advance(end + 4)
+
+	target, label, hasLabel := strings.Cut(inner, "|")
+	if !hasLabel {
+		label = inner
+	}
+	target, fragment, _ := strings.Cut(target, "#")
+
+	site := res.Site
+	var dest string
+	if target == "" {
+		// [[#heading]] links within the same page
+		dest = ""
+	} else if linked := site.ResolveRef(res, target); linked != nil {
+		dest = site.resourceLink(linked)
+	} else {
+		site.addUnresolvedRef(res, target)
+		missing := ast.NewString([]byte(`<span class="wikilink missing">` + htmpl.HTMLEscapeString(label) + `</span>`))
+		missing.SetCode(true)
+		return missing
+	}
+	if fragment = strings.TrimSpace(fragment); fragment != "" {
+		// The same id goldmark's auto heading ids give the heading (in a new
+		// context so the ids of this page are not affected)
+		dest += "#" + string(parser.NewContext().IDs().Generate([]byte(fragment), ast.KindHeading))
+	}
+
+	link := ast.NewLink()
+	link.Destination = []byte(dest)
+	link.SetAttributeString("class", []byte("wikilink"))
+	link.AppendChild(link, ast.NewString([]byte(label)))
+	return link
+}
@@ -0,0 +1,225 @@
+package s3gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// RelatedConfig holds the weights used to score how related two pages are
+// for the Related template function. Each signal scores between 0 and 1
+// (the share of tags, terms or words the pages have in common, or how close
+// their dates are) and is multiplied by its weight. Set a weight to a
+// negative value to ignore the signal.
+type RelatedConfig struct {
+	// Tags is the weight of shared tags (default 1)
+	Tags float64
+
+	// Taxonomies is the weight of shared terms of the site's Taxonomies
+	// (default 1)
+	Taxonomies float64
+
+	// Title is the weight of shared words in titles (default 0.5)
+	Title float64
+
+	// Keywords is the weight of shared keywords front matter values
+	// (default 1)
+	Keywords float64
+
+	// Date is the weight of date proximity (default 0.25). It only adds to
+	// the score of pages that already share tags, terms or words.
+	Date float64
+
+	// DateRange is the date difference at which date proximity scores 0
+	// (default a year)
+	DateRange time.Duration
+}
+
+// relatedWeight returns a weight, or its default if not set.
+func relatedWeight(weight, def float64) float64 {
+	if weight == 0 {
+		return def
+	}
+	return max(0, weight)
+}
+
+// weights returns the config with the defaults of unset weights.
+func (c *RelatedConfig) weights() (out RelatedConfig) {
+	if c != nil {
+		out = *c
+	}
+	out.Tags = relatedWeight(out.Tags, 1)
+	out.Taxonomies = relatedWeight(out.Taxonomies, 1)
+	out.Title = relatedWeight(out.Title, 0.5)
+	out.Keywords = relatedWeight(out.Keywords, 1)
+	out.Date = relatedWeight(out.Date, 0.25)
+	if out.DateRange <= 0 {
+		out.DateRange = 365 * 24 * time.Hour
+	}
+	return
+}
+
+// relatedStopWords are the title words ignored when scoring related pages.
+var relatedStopWords = map[string]bool{
+	"and": true, "are": true, "but": true, "can": true, "for": true, "from": true,
+	"how": true, "into": true, "its": true, "not": true, "one": true, "our": true,
+	"that": true, "the": true, "this": true, "use": true, "using": true, "was": true,
+	"what": true, "when": true, "why": true, "with": true, "you": true, "your": true,
+}
+
+// relatedFeatures are the signals of a page compared by the related content
+// scoring.
+type relatedFeatures struct {
+	tags     map[string]bool
+	terms    map[string]bool
+	title    map[string]bool
+	keywords map[string]bool
+	date     time.Time
+}
+
+// pageRelatedFeatures returns the tags, taxonomy terms, title words,
+// keywords and date of a page.
+func (s *Site) pageRelatedFeatures(res *Resource) *relatedFeatures {
+	base := res.Base.(*DefaultResourceBase)
+	fm := res.FrontMatter().Data
+	out := &relatedFeatures{
+		tags:     map[string]bool{},
+		terms:    map[string]bool{},
+		title:    map[string]bool{},
+		keywords: map[string]bool{},
+		date:     pageDate(res),
+	}
+	for _, tag := range base.Tags {
+		if slug := gotl.Slugify(tag); slug != "" {
+			out.tags[slug] = true
+		}
+	}
+	for name, tt := range s.taxonomyTerms {
+		if name == "tags" {
+			// Already scored as tags
+			continue
+		}
+		for _, value := range pageTerms(fm, name) {
+			if _, slug := tt.termName(value); slug != "" {
+				out.terms[name+"/"+slug] = true
+			}
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(base.Title), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		if len(word) > 2 && !relatedStopWords[word] {
+			out.title[word] = true
+		}
+	}
+	keywords := pageTerms(fm, "keywords")
+	if len(keywords) == 1 {
+		// Keywords are often given as a comma separated string
+		keywords = strings.Split(keywords[0], ",")
+	}
+	for _, keyword := range keywords {
+		if slug := gotl.Slugify(strings.TrimSpace(fmt.Sprint(keyword))); slug != "" {
+			out.keywords[slug] = true
+		}
+	}
+	return out
+}
+
+// overlap returns the share of values two sets have in common (0 to 1).
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for v := range a {
+		if b[v] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// score returns how related two pages are, or 0 if they share nothing.
+func (f *relatedFeatures) score(other *relatedFeatures, w RelatedConfig) float64 {
+	score := w.Tags*overlap(f.tags, other.tags) +
+		w.Taxonomies*overlap(f.terms, other.terms) +
+		w.Title*overlap(f.title, other.title) +
+		w.Keywords*overlap(f.keywords, other.keywords)
+	if score <= 0 {
+		return 0
+	}
+	if !f.date.IsZero() && !other.date.IsZero() {
+		diff := f.date.Sub(other.date).Abs()
+		score += w.Date * max(0, 1-float64(diff)/float64(w.DateRange))
+	}
+	return score
+}
+
+// buildRelated scores every pair of pages and keeps the related pages of
+// each page, most related first. Drafts, section index pages and paginated
+// list pages are not related to other pages.
+func (s *Site) buildRelated() {
+	s.related = map[*Resource][]*Resource{}
+	weights := s.RelatedContent.weights()
+
+	listPages := map[*Resource]bool{}
+	for _, section := range s.sections {
+		if section.Page != nil {
+			listPages[section.Page] = true
+		}
+	}
+	var pages []*Resource
+	features := map[*Resource]*relatedFeatures{}
+	for _, res := range s.pages {
+		if res.Base.(*DefaultResourceBase).IsDraft || listPages[res] || isPaginated(res) {
+			continue
+		}
+		pages = append(pages, res)
+		features[res] = s.pageRelatedFeatures(res)
+	}
+
+	scores := map[*Resource]map[*Resource]float64{}
+	for i, r1 := range pages {
+		for _, r2 := range pages[i+1:] {
+			score := features[r1].score(features[r2], weights)
+			if score <= 0 {
+				continue
+			}
+			for _, pair := range [][2]*Resource{{r1, r2}, {r2, r1}} {
+				if scores[pair[0]] == nil {
+					scores[pair[0]] = map[*Resource]float64{}
+				}
+				scores[pair[0]][pair[1]] = score
+				s.related[pair[0]] = append(s.related[pair[0]], pair[1])
+			}
+		}
+	}
+
+	for res, related := range s.related {
+		sort.SliceStable(related, func(i, j int) bool {
+			r1, r2 := related[i], related[j]
+			if s1, s2 := scores[res][r1], scores[res][r2]; s1 != s2 {
+				return s1 > s2
+			}
+			if d1, d2 := pageDate(r1), pageDate(r2); !d1.Equal(d2) {
+				return d2.Before(d1)
+			}
+			return r1.Base.(*DefaultResourceBase).Title < r2.Base.(*DefaultResourceBase).Title
+		})
+	}
+}
+
+// Related returns up to n pages related to a page, most related first (all
+// of them if n is 0). Pages are related by their shared tags, taxonomy
+// terms, title words and keywords, and by how close their dates are,
+// weighted by Site.RelatedContent.
+func (s *Site) Related(res *Resource, n int) []*Resource {
+	related := s.related[res]
+	if n > 0 && n < len(related) {
+		return related[:n]
+	}
+	return related
+}
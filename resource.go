@@ -3,8 +3,10 @@ package s3gen
 import (
 	"encoding/json"
 	"fmt"
+	htmpl "html/template"
 	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -139,9 +141,24 @@ type Resource struct {
 	IsParametric bool
 
 	// ParamValues is the list of parameter values for a parametric page.
+	// For pages with several params, eg [year]/[month]/[slug].md, each value
+	// holds the values of all params joined by "/", eg "2024/05/hello".
 	ParamValues []string
+	// ParamSets are the values of each param, keyed by param name, for
+	// pages with several params (see AddParamSet).
+	ParamSets []map[string]string
 	// ParamName is the name of the parameter for a parametric page.
 	ParamName string
+	// Params maps the names of the params of a parametric page to their
+	// values for the page being rendered, eg {"year": "2024", "slug": "hello"}.
+	Params map[string]string
+
+	// Paginator is the page of items being rendered for a paginated list page.
+	Paginator *Paginator
+
+	// Item is the data record being rendered for a page generated from a
+	// data file (see the items front matter of parametric pages).
+	Item any
 
 	// NeedsIndex is true if the resource should be rendered as an index page.
 	NeedsIndex bool
@@ -173,6 +190,10 @@ func (r *Resource) Reset() {
 	r.Document.Loaded = false
 	r.Base = nil
 	r.ParamValues = nil
+	r.ParamSets = nil
+	r.Params = nil
+	r.Paginator = nil
+	r.Item = nil
 	r.Assets = nil
 	r.AssetOf = nil
 	r.ProducedBy = nil
@@ -195,6 +216,22 @@ func (r *Resource) SetMetadata(key string, value any, kvpairs ...any) any {
 	return ""
 }
 
+// renderedContentKey is the Document.Metadata key holding the HTML rendered
+// from a resource's content.
+const renderedContentKey = "content"
+
+// markdownContentKey is the Document.Metadata key holding the markdown of a
+// resource after its templates have been applied.
+const markdownContentKey = "markdown"
+
+// RenderedContent returns the HTML rendered from the resource's content,
+// without the page template around it. It is empty until the resource has
+// been generated.
+func (r *Resource) RenderedContent() htmpl.HTML {
+	content, _ := r.Document.Metadata[renderedContentKey].(htmpl.HTML)
+	return content
+}
+
 // EnsureDir ensures that the resource's parent directory exists.
 func (r *Resource) EnsureDir() {
 	dirname := filepath.Dir(r.FullPath)
@@ -284,7 +321,7 @@ func (r *Resource) FrontMatter() *FrontMatter {
 			// this way we dont need to load the entire content unless we needed
 			// and even then we could just do it via a reader
 			// rest, err := frontmatter.Parse(f, r.frontMatter.Data)
-			rest, err := frontmatter.Parse(f, r.frontMatter.Data, DefaultFormats...)
+			rest, err := frontmatter.Parse(f, &r.frontMatter.Data, DefaultFormats...)
 			r.frontMatter.Length = r.Info().Size() - int64(len(rest))
 			if err != nil {
 				r.Error = err
@@ -310,6 +347,33 @@ func (r *Resource) AddParams(params []string) *Resource {
 	return r
 }
 
+// AddParamSet adds the values of all params of a page with several params,
+// eg [year]/[month]/[slug].md, either as a map of param names to values (eg
+// from dict) or as the values in the order of the params in the path.
+func (r *Resource) AddParamSet(values ...any) *Resource {
+	set := map[string]string{}
+	if len(values) == 1 {
+		switch m := values[0].(type) {
+		case map[string]any:
+			for name, value := range m {
+				set[name] = fmt.Sprint(value)
+			}
+		case map[string]string:
+			maps.Copy(set, m)
+		}
+	}
+	if len(set) == 0 {
+		names := paramNames(r.RelPath())
+		for i, value := range values {
+			if i < len(names) {
+				set[names[i]] = fmt.Sprint(value)
+			}
+		}
+	}
+	r.ParamSets = append(r.ParamSets, set)
+	return r
+}
+
 // RelPath returns the path of the resource relative to the content root.
 func (r *Resource) RelPath() string {
 	respath, found := strings.CutPrefix(r.FullPath, r.Site.ContentRoot)
@@ -319,6 +383,95 @@ func (r *Resource) RelPath() string {
 	return respath
 }
 
+// isIndexPath returns true if the file name of a path (without extensions)
+// marks an index page, eg index.md or _index.html.
+func isIndexPath(fullpath string) bool {
+	base := filepath.Base(fullpath)
+	base = base[:len(base)-len(filepath.Ext(base))]
+	return base == "index" || base == "_index" || base == "Index"
+}
+
+// dateLayouts are the layouts accepted for dates in front matter.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-1-2T03:04:05PM",
+	"2006-1-2T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-1-2",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// parseDate parses a front matter date, which is either a time (as decoded
+// by the YAML and TOML parsers) or a string in one of dateLayouts.
+func parseDate(val any) (time.Time, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		v = strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// frontMatterValue returns the value at a path of keys in front matter data,
+// eg frontMatterValue(fm, "sitemap", "priority"). Nested maps may be decoded
+// as map[string]any or map[any]any depending on the front matter format.
+func frontMatterValue(data any, keys ...string) (any, bool) {
+	for _, key := range keys {
+		var ok bool
+		switch m := data.(type) {
+		case map[string]any:
+			data, ok = m[key]
+		case map[any]any:
+			data, ok = m[key]
+		}
+		if !ok || data == nil {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// paramNames returns the names of the params in a path relative to the
+// content root, from the bracketed dirs and file name, eg [year, month,
+// slug] for blog/[year]/[month]/[slug].md.
+func paramNames(respath string) (out []string) {
+	segments := strings.Split(filepath.ToSlash(respath), "/")
+	base := segments[len(segments)-1]
+	for ext := filepath.Ext(base); ext != ""; ext = filepath.Ext(base) {
+		base = base[:len(base)-len(ext)]
+	}
+	segments[len(segments)-1] = base
+	for _, segment := range segments {
+		if len(segment) > 2 && segment[0] == '[' && segment[len(segment)-1] == ']' {
+			out = append(out, segment[1:len(segment)-1])
+		}
+	}
+	return
+}
+
+// isParametricPath returns true if the file name of a path (without
+// extensions) is a parameter placeholder, eg [tag].html.
+func isParametricPath(fullpath string) bool {
+	base := filepath.Base(fullpath)
+	for ext := filepath.Ext(base); ext != ""; ext = filepath.Ext(base) {
+		base = base[:len(base)-len(ext)]
+	}
+	return len(base) > 1 && base[0] == '[' && base[len(base)-1] == ']'
+}
+
 // ResourceFilterFunc is a function type for filtering resources.
 type ResourceFilterFunc func(res *Resource) bool
 
@@ -0,0 +1,134 @@
+package s3gen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RobotsGenerator writes a robots.txt in the Finalize phase from a list of
+// rules and links to the site's sitemaps.
+type RobotsGenerator struct {
+	// BaseURL is the base URL for the site (e.g., "https://example.com")
+	BaseURL string
+
+	// OutputPath is the path to write robots.txt (default: "robots.txt")
+	OutputPath string
+
+	// Rules are the groups of rules for crawlers. Default: allow all
+	// crawlers everything.
+	Rules []RobotsRule
+
+	// Sitemaps are the paths (relative to OutputDir) of the sitemaps to link
+	// (default: "sitemap.xml", which a SitemapGenerator writes as a sitemap
+	// or a sitemap index)
+	Sitemaps []string
+}
+
+// RobotsRule is a group of robots.txt rules for one or more crawlers.
+type RobotsRule struct {
+	// UserAgents are the crawlers the rule applies to (default: "*")
+	UserAgents []string
+
+	// Allow and Disallow are URL path prefixes crawlers may or may not visit
+	Allow    []string
+	Disallow []string
+
+	// CrawlDelay is the number of seconds to wait between requests
+	CrawlDelay int
+}
+
+// Phase returns PhaseFinalize - robots.txt is written after all content is generated.
+func (g *RobotsGenerator) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns nil - sitemaps are linked by their configured paths.
+func (g *RobotsGenerator) DependsOn() []string {
+	return nil
+}
+
+// Produces returns the robots.txt pattern.
+func (g *RobotsGenerator) Produces() []string {
+	return []string{"robots.txt"}
+}
+
+// TargetsFor returns nil - RobotsGenerator uses hooks instead of per-resource targets.
+func (g *RobotsGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (g *RobotsGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the robots.txt generator to a site.
+func (g *RobotsGenerator) Register(site *Site) {
+	// Set defaults
+	if g.OutputPath == "" {
+		g.OutputPath = "robots.txt"
+	}
+	if len(g.Rules) == 0 {
+		g.Rules = []RobotsRule{{Allow: []string{"/"}}}
+	}
+	if len(g.Sitemaps) == 0 {
+		g.Sitemaps = []string{"sitemap.xml"}
+	}
+
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		if err := g.writeRobots(ctx.Site); err != nil {
+			ctx.AddError(fmt.Errorf("robots.txt generation failed: %w", err))
+		}
+	})
+}
+
+func (g *RobotsGenerator) writeRobots(site *Site) error {
+	var b strings.Builder
+	for i, rule := range g.Rules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		agents := rule.UserAgents
+		if len(agents) == 0 {
+			agents = []string{"*"}
+		}
+		for _, agent := range agents {
+			fmt.Fprintf(&b, "User-agent: %s\n", agent)
+		}
+		for _, path := range rule.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", path)
+		}
+		for _, path := range rule.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", path)
+		}
+		if len(rule.Allow) == 0 && len(rule.Disallow) == 0 {
+			// An empty Disallow allows everything
+			b.WriteString("Disallow:\n")
+		}
+		if rule.CrawlDelay > 0 {
+			fmt.Fprintf(&b, "Crawl-delay: %d\n", rule.CrawlDelay)
+		}
+	}
+
+	// Link the sitemaps by their configured paths as they may not be written
+	// yet when this runs
+	if len(g.Sitemaps) > 0 {
+		b.WriteString("\n")
+		for _, sitemap := range g.Sitemaps {
+			loc := site.PathRelUrl("/" + strings.TrimPrefix(filepath.ToSlash(sitemap), "/"))
+			fmt.Fprintf(&b, "Sitemap: %s%s\n", strings.TrimSuffix(g.BaseURL, "/"), loc)
+		}
+	}
+
+	outPath := filepath.Join(site.OutputDir, g.OutputPath)
+	log.Printf("[Robots] Writing %s", outPath)
+	return os.WriteFile(outPath, []byte(b.String()), 0644)
+}
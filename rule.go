@@ -142,30 +142,9 @@ func (h *BaseToHtmlRule) HandleAssets(site *Site, res *Resource, assets []*Resou
 	var mappings []AssetMapping
 
 	for _, asset := range assets {
-		var destPath string
-
-		if res.IsParametric {
-			// Parametric pages: assets go to shared folder with content-hash
-			hash := ContentHashShort(asset)
-			destPath = filepath.Join(site.SharedAssetsDir, hash, filepath.Base(asset.FullPath))
-		} else {
-			// Non-parametric: co-locate with output
-			respath := res.RelPath()
-			if respath == "" {
-				continue
-			}
-
-			ext := filepath.Ext(respath)
-			rem := respath[:len(respath)-len(ext)]
-
-			var destDir string
-			if res.IsIndex {
-				destDir = filepath.Dir(respath)
-			} else {
-				destDir = rem
-			}
-
-			destPath = filepath.Join(destDir, filepath.Base(asset.FullPath))
+		destPath := assetDestPath(site, res, asset)
+		if destPath == "" {
+			continue
 		}
 
 		mappings = append(mappings, AssetMapping{
@@ -202,8 +181,7 @@ func (h *BaseToHtmlRule) LoadResource(site *Site, r *Resource) error {
 		r.NeedsIndex = true
 	}
 
-	base = filepath.Base(r.WithoutExt(true))
-	r.IsParametric = base[0] == '[' && base[len(base)-1] == ']'
+	r.IsParametric = isParametricPath(r.FullPath)
 
 	// TODO - this needs to go - nothing magical about "Base"
 	r.Site.CreateResourceBase(r)
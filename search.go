@@ -0,0 +1,474 @@
+package s3gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchIndexGenerator builds a client side search index in the Finalize
+// phase. The title, URL, tags, headings and text of every generated page are
+// collected into an inverted index which is split into shards by term prefix,
+// so a query only downloads the shards for its terms. The index is written as
+// JSON files along with a small query script (search.js) into OutputPath.
+type SearchIndexGenerator struct {
+	// OutputPath is the directory for the index files, relative to OutputDir
+	// (default: "search")
+	OutputPath string
+
+	// ExcludePatterns are glob patterns (relative to OutputDir) for pages to
+	// leave out of the index
+	ExcludePatterns []string
+
+	// IncludeDrafts includes pages with draft: true in the index
+	IncludeDrafts bool
+
+	// ShardSize is the approximate number of terms in each index shard
+	// (default: 2000)
+	ShardSize int
+
+	// DocsPerShard is the number of documents in each document shard
+	// (default: 500)
+	DocsPerShard int
+
+	// SummaryLength is the number of characters of text stored with each
+	// document for showing in results (default: 200)
+	SummaryLength int
+
+	// pages collected during the build
+	pages []searchPage
+}
+
+type searchPage struct {
+	res    *Resource
+	target *Resource
+}
+
+// searchDoc is a document in the index as written to the docs shards.
+type searchDoc struct {
+	URL      string   `json:"url"`
+	Title    string   `json:"title"`
+	Tags     []string `json:"tags,omitempty"`
+	Headings []string `json:"headings,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+}
+
+// searchMeta describes how the index is sharded.
+type searchMeta struct {
+	DocsPerShard int            `json:"docsPerShard"`
+	DocCount     int            `json:"docCount"`
+	Prefixes     map[string]int `json:"prefixes"`
+}
+
+// Weights of terms found in different parts of a page
+const (
+	searchWeightText    = 1
+	searchWeightHeading = 3
+	searchWeightTag     = 5
+	searchWeightTitle   = 10
+)
+
+// searchPrefixLength is the number of runes of a term used to pick its shard.
+const searchPrefixLength = 2
+
+var (
+	searchSkipPattern    = regexp.MustCompile(`(?is)<!--.*?-->|<a\b[^>]*class="anchor"[^>]*>.*?</a>|<(script|style|noscript|template|nav|header|footer)\b.*?</(script|style|noscript|template|nav|header|footer)>`)
+	searchTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+	searchHeadingPattern = regexp.MustCompile(`(?is)<h[1-6]\b[^>]*>(.*?)</h[1-6]>`)
+	searchTitlePattern   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title>`)
+	searchMainPatterns   = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<main\b[^>]*>(.*)</main>`),
+		regexp.MustCompile(`(?is)<article\b[^>]*>(.*)</article>`),
+		regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body>`),
+	}
+)
+
+// Phase returns PhaseFinalize - the index is built after all content is generated.
+func (g *SearchIndexGenerator) Phase() BuildPhase {
+	return PhaseFinalize
+}
+
+// DependsOn returns patterns for HTML files - all pages must be generated first.
+func (g *SearchIndexGenerator) DependsOn() []string {
+	return []string{"**/*.html"}
+}
+
+// Produces returns the patterns of the index files.
+func (g *SearchIndexGenerator) Produces() []string {
+	return []string{g.OutputPath + "/*.json", g.OutputPath + "/search.js"}
+}
+
+// TargetsFor returns nil - SearchIndexGenerator uses hooks instead of per-resource targets.
+func (g *SearchIndexGenerator) TargetsFor(site *Site, res *Resource) ([]*Resource, []*Resource) {
+	return nil, nil
+}
+
+// Run is a no-op - actual work is done via hooks.
+func (g *SearchIndexGenerator) Run(site *Site, inputs []*Resource, targets []*Resource, funcs map[string]any) error {
+	return nil
+}
+
+// Register adds the search index generator to a site.
+func (g *SearchIndexGenerator) Register(site *Site) {
+	// Set defaults
+	if g.OutputPath == "" {
+		g.OutputPath = "search"
+	}
+	if g.ShardSize <= 0 {
+		g.ShardSize = 2000
+	}
+	if g.DocsPerShard <= 0 {
+		g.DocsPerShard = 500
+	}
+	if g.SummaryLength <= 0 {
+		g.SummaryLength = 200
+	}
+
+	// Initialize hooks if needed
+	if site.Hooks == nil {
+		site.Hooks = NewHookRegistry()
+	}
+
+	// Reset pages at start of build
+	site.Hooks.OnPhaseStart(PhaseDiscover, func(ctx *BuildContext) {
+		g.pages = nil
+	})
+
+	// Collect pages as resources are processed. They are read at the end of
+	// the build as pages may be rendered more than once.
+	site.Hooks.OnResourceProcessed(func(ctx *BuildContext, res *Resource, targets []*Resource) {
+		if res == nil {
+			return
+		}
+		for _, target := range targets {
+			if !strings.HasSuffix(target.FullPath, ".html") || isLaterPage(target) {
+				continue
+			}
+			relPath, err := filepath.Rel(ctx.Site.OutputDir, target.FullPath)
+			if err != nil || g.shouldExclude(relPath) {
+				continue
+			}
+			// Only pages have front matter, not stylesheets, images etc
+			if !g.IncludeDrafts && res.FrontMatter().Data["draft"] == true {
+				return
+			}
+			g.pages = append(g.pages, searchPage{res: res, target: target})
+		}
+	})
+
+	// Write index at end of Finalize phase
+	site.Hooks.OnPhaseEnd(PhaseFinalize, func(ctx *BuildContext) {
+		if err := g.writeIndex(ctx.Site); err != nil {
+			ctx.AddError(fmt.Errorf("search index generation failed: %w", err))
+		}
+	})
+}
+
+func (g *SearchIndexGenerator) shouldExclude(path string) bool {
+	for _, pattern := range g.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// document extracts the searchable parts of a generated page along with the
+// weighted terms found in it.
+func (g *SearchIndexGenerator) document(site *Site, page searchPage) (doc searchDoc, terms map[string]int, err error) {
+	data, err := os.ReadFile(page.target.FullPath)
+	if err != nil {
+		return
+	}
+	doc.URL = site.TargetURL(page.target)
+
+	if fm := page.res.FrontMatter(); fm != nil && fm.Data != nil {
+		doc.Title, _ = fm.Data["title"].(string)
+		if tags, ok := fm.Data["tags"].([]any); ok {
+			for _, tag := range tags {
+				if t, ok := tag.(string); ok {
+					doc.Tags = append(doc.Tags, t)
+				}
+			}
+		}
+	}
+	if doc.Title == "" {
+		if m := searchTitlePattern.FindSubmatch(data); m != nil {
+			doc.Title = htmlToText(m[1])
+		}
+	}
+
+	// Only index the main content of the page if it can be found
+	content := searchSkipPattern.ReplaceAll(data, nil)
+	for _, p := range searchMainPatterns {
+		if m := p.FindSubmatch(content); m != nil {
+			content = m[1]
+			break
+		}
+	}
+	for _, m := range searchHeadingPattern.FindAllSubmatch(content, -1) {
+		if heading := strings.Trim(htmlToText(m[1]), " ¶#"); heading != "" {
+			doc.Headings = append(doc.Headings, heading)
+		}
+	}
+	text := htmlToText(content)
+	doc.Summary = text
+	if runes := []rune(text); len(runes) > g.SummaryLength {
+		doc.Summary = strings.TrimSpace(string(runes[:g.SummaryLength])) + "…"
+	}
+
+	terms = map[string]int{}
+	addTerms := func(s string, weight int) {
+		for _, term := range searchTerms(s) {
+			terms[term] += weight
+		}
+	}
+	addTerms(text, searchWeightText)
+	addTerms(strings.Join(doc.Headings, " "), searchWeightHeading)
+	addTerms(strings.Join(doc.Tags, " "), searchWeightTag)
+	addTerms(doc.Title, searchWeightTitle)
+	return
+}
+
+func (g *SearchIndexGenerator) writeIndex(site *Site) error {
+	if len(g.pages) == 0 {
+		return nil
+	}
+
+	// Pages can be processed more than once - keep the last
+	seen := map[string]int{}
+	var pages []searchPage
+	for _, page := range g.pages {
+		if i, ok := seen[page.target.FullPath]; ok {
+			pages[i] = page
+			continue
+		}
+		seen[page.target.FullPath] = len(pages)
+		pages = append(pages, page)
+	}
+
+	var docs []searchDoc
+	postings := map[string][][2]int{}
+	for _, page := range pages {
+		doc, terms, err := g.document(site, page)
+		if err != nil {
+			return err
+		}
+		id := len(docs)
+		docs = append(docs, doc)
+		for term, score := range terms {
+			postings[term] = append(postings[term], [2]int{id, score})
+		}
+	}
+
+	outDir := filepath.Join(site.OutputDir, g.OutputPath)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	// Document shards
+	for start := 0; start < len(docs); start += g.DocsPerShard {
+		end := min(start+g.DocsPerShard, len(docs))
+		if err := writeJSON(filepath.Join(outDir, fmt.Sprintf("docs-%d.json", start/g.DocsPerShard)), docs[start:end]); err != nil {
+			return err
+		}
+	}
+
+	// Group terms by prefix and pack the prefixes into shards
+	byPrefix := map[string]map[string][][2]int{}
+	for term, p := range postings {
+		prefix := searchPrefix(term)
+		if byPrefix[prefix] == nil {
+			byPrefix[prefix] = map[string][][2]int{}
+		}
+		byPrefix[prefix][term] = p
+	}
+	prefixes := make([]string, 0, len(byPrefix))
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	meta := searchMeta{DocsPerShard: g.DocsPerShard, DocCount: len(docs), Prefixes: map[string]int{}}
+	shard := map[string][][2]int{}
+	shardNum := 0
+	flush := func() error {
+		if len(shard) == 0 {
+			return nil
+		}
+		err := writeJSON(filepath.Join(outDir, fmt.Sprintf("index-%d.json", shardNum)), shard)
+		shard = map[string][][2]int{}
+		shardNum++
+		return err
+	}
+	for _, prefix := range prefixes {
+		if len(shard) > 0 && len(shard)+len(byPrefix[prefix]) > g.ShardSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		for term, p := range byPrefix[prefix] {
+			shard[term] = p
+		}
+		meta.Prefixes[prefix] = shardNum
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// An earlier build may have written more shards
+	docShards := (len(docs) + g.DocsPerShard - 1) / g.DocsPerShard
+	if err := removeStaleShards(outDir, "docs", docShards); err != nil {
+		return err
+	}
+	if err := removeStaleShards(outDir, "index", shardNum); err != nil {
+		return err
+	}
+
+	if err := writeJSON(filepath.Join(outDir, "meta.json"), meta); err != nil {
+		return err
+	}
+	script := strings.NewReplacer(
+		"__STOP_WORDS__", strings.Join(searchStopWords, " "),
+		"__PREFIX_LENGTH__", fmt.Sprint(searchPrefixLength),
+	).Replace(searchScript)
+	if err := os.WriteFile(filepath.Join(outDir, "search.js"), []byte(script), 0644); err != nil {
+		return err
+	}
+	log.Printf("[Search] Indexed %d pages, %d terms in %d shards", len(docs), len(postings), shardNum)
+	return nil
+}
+
+// removeStaleShards removes the <name>-N.json shards in outDir numbered
+// count or above.
+func removeStaleShards(outDir, name string, count int) error {
+	paths, err := filepath.Glob(filepath.Join(outDir, name+"-*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		var n int
+		if _, err := fmt.Sscanf(filepath.Base(path), name+"-%d.json", &n); err == nil && n >= count {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// htmlToText strips tags from HTML markup and collapses whitespace.
+func htmlToText(data []byte) string {
+	text := html.UnescapeString(string(searchTagPattern.ReplaceAll(data, []byte(" "))))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// searchTerms splits text into lower cased terms. search.js tokenizes
+// queries the same way.
+func searchTerms(text string) (out []string) {
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len([]rune(term)) >= 2 && !slices.Contains(searchStopWords, term) {
+			out = append(out, term)
+		}
+	}
+	return
+}
+
+func searchPrefix(term string) string {
+	runes := []rune(term)
+	return string(runes[:min(len(runes), searchPrefixLength)])
+}
+
+var searchStopWords = []string{
+	"an", "and", "are", "as", "at", "be", "by", "for", "from", "in", "is",
+	"it", "of", "on", "or", "that", "the", "this", "to", "was", "with",
+}
+
+// searchScript is written as search.js next to the index. It defines
+// S3Search.search(query, limit) which returns a promise of the matching
+// documents, best first. All terms must match; the last term also matches
+// as a prefix so results can be shown while typing.
+const searchScript = `(function () {
+  var base = (document.currentScript && document.currentScript.src || "").replace(/[^/]*$/, "");
+  var stopWords = "__STOP_WORDS__".split(" ");
+  var cache = {};
+
+  function load(name) {
+    if (!cache[name]) {
+      cache[name] = fetch(base + name).then(function (r) {
+        if (!r.ok) throw new Error("failed to load " + name);
+        return r.json();
+      });
+    }
+    return cache[name];
+  }
+
+  function terms(text) {
+    return text.toLowerCase().split(/[^\p{L}\p{N}]+/u).filter(function (t) {
+      return Array.from(t).length >= 2 && stopWords.indexOf(t) < 0;
+    });
+  }
+
+  function prefix(term) {
+    return Array.from(term).slice(0, __PREFIX_LENGTH__).join("");
+  }
+
+  function search(query, limit) {
+    limit = limit || 20;
+    var qterms = terms(query);
+    if (qterms.length === 0) return Promise.resolve([]);
+    return load("meta.json").then(function (meta) {
+      return Promise.all(qterms.map(function (term, i) {
+        var shard = meta.prefixes[prefix(term)];
+        if (shard === undefined) return {};
+        return load("index-" + shard + ".json").then(function (index) {
+          var scores = {};
+          Object.keys(index).forEach(function (t) {
+            var last = i === qterms.length - 1;
+            if (t === term || (last && t.indexOf(term) === 0)) {
+              index[t].forEach(function (p) {
+                scores[p[0]] = Math.max(scores[p[0]] || 0, t === term ? p[1] : p[1] / 2);
+              });
+            }
+          });
+          return scores;
+        });
+      })).then(function (perTerm) {
+        var total = perTerm[0];
+        perTerm.slice(1).forEach(function (scores) {
+          Object.keys(total).forEach(function (id) {
+            if (scores[id] === undefined) delete total[id];
+            else total[id] += scores[id];
+          });
+        });
+        var ids = Object.keys(total).sort(function (a, b) { return total[b] - total[a]; }).slice(0, limit);
+        return Promise.all(ids.map(function (id) {
+          return load("docs-" + Math.floor(id / meta.docsPerShard) + ".json").then(function (docs) {
+            return Object.assign({ score: total[id] }, docs[id % meta.docsPerShard]);
+          });
+        }));
+      });
+    });
+  }
+
+  window.S3Search = { search: search };
+})();
+`
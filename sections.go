@@ -0,0 +1,227 @@
+package s3gen
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Section is a content dir in the section tree of the site. A dir is a
+// section if it has pages, and its index page (_index or index file) gives
+// the section its title, link and weight. Dirs with a single index page and
+// no other pages are page bundles and so pages of their parent section.
+type Section struct {
+	// Dir is the dir of the section relative to ContentRoot ("" for the
+	// root section)
+	Dir string
+
+	// Page is the index page of the section, or nil if it has none
+	Page *Resource
+
+	// Title is the title of the index page, or the name of the dir
+	Title string
+
+	// Link is the link of the index page, or empty if it has none
+	Link string
+
+	// Weight is the weight front matter field of the index page, used to
+	// order sections
+	Weight int
+
+	// Parent is the parent section, or nil for the root section
+	Parent *Section
+
+	// Sections are the sub sections, ordered by weight, then title
+	Sections []*Section
+
+	// Pages are the pages of the section (not including its index page or
+	// drafts), ordered by weight, then date, then title
+	Pages []*Resource
+}
+
+// Contains returns true if a page is the index page or a page of the
+// section or of one of its sub sections, eg to expand the section in a
+// sidebar.
+func (s *Section) Contains(res *Resource) bool {
+	if res == nil {
+		return false
+	}
+	if s.Page == res || slices.Contains(s.Pages, res) {
+		return true
+	}
+	for _, sub := range s.Sections {
+		if sub.Contains(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ancestors returns the sections above this section, from the root section
+// down to its parent.
+func (s *Section) Ancestors() (out []*Section) {
+	for p := s.Parent; p != nil; p = p.Parent {
+		out = append(out, p)
+	}
+	slices.Reverse(out)
+	return
+}
+
+// isSectionPage returns true if a page is the index page of its dir's
+// section rather than a page in it. _index files always are, and index files
+// are unless they are page bundles (the only page in their dir tree).
+func isSectionPage(res *Resource, hasPages map[string]bool) bool {
+	name := filepath.Base(res.FullPath)
+	if strings.HasPrefix(name, "_index.") {
+		return true
+	}
+	return isIndexPath(res.FullPath) && (hasPages[filepath.Dir(res.FullPath)] || filepath.Dir(res.FullPath) == res.Site.ContentRoot)
+}
+
+// pageSectionDir returns the content dir of the section a page is in,
+// relative to ContentRoot ("" for the top level). Page bundles are in the
+// section of their parent dir.
+func (s *Site) pageSectionDir(res *Resource) string {
+	dir := filepath.Dir(res.FullPath)
+	if isIndexPath(res.FullPath) && dir != s.ContentRoot {
+		dir = filepath.Dir(dir)
+	}
+	return s.contentDir(dir)
+}
+
+// contentDir returns the dir of a path relative to ContentRoot ("" for
+// ContentRoot itself).
+func (s *Site) contentDir(dir string) string {
+	rel, err := filepath.Rel(s.ContentRoot, dir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// section returns the section of a content dir, creating it and its parents
+// if needed.
+func (s *Site) section(dir string) *Section {
+	if section := s.sections[dir]; section != nil {
+		return section
+	}
+	section := &Section{Dir: dir, Title: filepath.Base(dir)}
+	if dir != "" {
+		parent := ""
+		if i := strings.LastIndex(dir, "/"); i >= 0 {
+			parent = dir[:i]
+		}
+		section.Parent = s.section(parent)
+		section.Parent.Sections = append(section.Parent.Sections, section)
+	} else {
+		section.Title = ""
+	}
+	s.sections[dir] = section
+	return section
+}
+
+// buildSections builds the section tree from the dirs of the content pages.
+func (s *Site) buildSections() {
+	s.sections = map[string]*Section{}
+	s.section("")
+
+	// Dirs with pages (other than their index pages) anywhere below them.
+	// Page bundles (dirs with an index page) count as pages of their parent
+	// dirs, so a dir of bundles is a section too.
+	hasPages := map[string]bool{}
+	for _, res := range s.pages {
+		dir := filepath.Dir(res.FullPath)
+		if strings.HasPrefix(filepath.Base(res.FullPath), "_index.") {
+			continue
+		} else if isIndexPath(res.FullPath) {
+			dir = filepath.Dir(dir)
+		}
+		for ; dir != s.ContentRoot && strings.HasPrefix(dir, s.ContentRoot); dir = filepath.Dir(dir) {
+			hasPages[dir] = true
+		}
+	}
+
+	for _, res := range s.pages {
+		base := res.Base.(*DefaultResourceBase)
+		if isSectionPage(res, hasPages) {
+			section := s.section(s.contentDir(filepath.Dir(res.FullPath)))
+			// _index files take precedence over index files
+			if section.Page == nil || strings.HasPrefix(filepath.Base(res.FullPath), "_index.") {
+				section.Page = res
+				section.Link = base.Link
+				section.Weight = pageWeight(res)
+				if base.Title != "" {
+					section.Title = base.Title
+				}
+			}
+			continue
+		}
+		if base.IsDraft {
+			continue
+		}
+		section := s.section(s.pageSectionDir(res))
+		section.Pages = append(section.Pages, res)
+	}
+
+	for _, section := range s.sections {
+		sortPages(section.Pages, pageWeight)
+		sort.SliceStable(section.Sections, func(i, j int) bool {
+			s1, s2 := section.Sections[i], section.Sections[j]
+			if s1.Weight != s2.Weight {
+				return s1.Weight < s2.Weight
+			}
+			return s1.Title < s2.Title
+		})
+	}
+}
+
+// SectionTree returns the root section of the site, whose sub sections are
+// the top level dirs of the content.
+func (s *Site) SectionTree() *Section {
+	return s.sections[""]
+}
+
+// Parent returns the section a page is in. For the index page of a section
+// this is the parent section.
+func (s *Site) Parent(res *Resource) *Section {
+	if res == nil {
+		return nil
+	}
+	for _, section := range s.sections {
+		if section.Page == res {
+			return section.Parent
+		}
+	}
+	return s.sections[s.pageSectionDir(res)]
+}
+
+// Ancestors returns the sections above a page, from the root section down to
+// the section the page is in, eg for breadcrumbs.
+func (s *Site) Ancestors(res *Resource) []*Section {
+	parent := s.Parent(res)
+	if parent == nil {
+		return nil
+	}
+	return append(parent.Ancestors(), parent)
+}
+
+// Children returns the pages below a section's index page: the index pages
+// of its sub sections followed by its pages. Other pages have no children.
+func (s *Site) Children(res *Resource) (out []*Resource) {
+	if res == nil {
+		return nil
+	}
+	for _, section := range s.sections {
+		if section.Page != res {
+			continue
+		}
+		for _, sub := range section.Sections {
+			if sub.Page != nil {
+				out = append(out, sub.Page)
+			}
+		}
+		return append(out, section.Pages...)
+	}
+	return nil
+}
@@ -3,14 +3,17 @@ package s3gen
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/felixge/httpsnoop"
@@ -44,6 +47,16 @@ type Site struct {
 	// OutputDir is the directory where the generated static files will be written.
 	OutputDir string
 
+	// DataDir is a directory of JSON, YAML, TOML and CSV data files that are
+	// loaded into Data once per build. Changes to it rebuild the site when
+	// watching.
+	DataDir string
+
+	// Data holds the contents of the files in DataDir, keyed by their path
+	// relative to DataDir without the extension, eg data/team/authors.yaml
+	// is at Data["team"]["authors"]. See the Data template function.
+	Data map[string]any
+
 	// PathPrefix is the URL path prefix for the site. For example, if your site
 	// is served at mydomain.com/blog, your PathPrefix would be "/blog".
 	PathPrefix string
@@ -73,6 +86,20 @@ type Site struct {
 	// DefaultBaseTemplate is the default template to use for rendering pages.
 	DefaultBaseTemplate BaseTemplate
 
+	// Taxonomies are the ways pages are classified by their front matter,
+	// eg categories, series or authors. See Taxonomy.
+	Taxonomies []Taxonomy
+
+	// Menus are the named menus of the site (eg "main" or "footer") and
+	// their configured entries. Pages can add themselves to menus with the
+	// menu front matter field. See MenuEntry.
+	Menus map[string][]MenuEntry
+
+	// RelatedContent holds the weights used to find related pages for the
+	// Related template function. Defaults are used if not set. See
+	// RelatedConfig.
+	RelatedContent *RelatedConfig
+
 	// GetTemplate is a function that can be used to override the default
 	// template for a specific resource.
 	GetTemplate func(res *Resource, out *BaseTemplate)
@@ -103,7 +130,10 @@ type Site struct {
 	// resources is a map of all the resources in the site, keyed by their
 	// full path.
 	resources map[string]*Resource
-	resedges  map[string][]string
+
+	// resedges holds the edges between resources, keyed by the full path of
+	// the source: their dependencies and the links between pages.
+	resedges map[string][]resEdge
 
 	initialized bool
 
@@ -122,11 +152,100 @@ type Site struct {
 	// SharedAssetsDir is the directory name for shared assets (used by parametric pages).
 	// Defaults to "_assets" if not set.
 	SharedAssetsDir string
+
+	// FingerprintAssets enables cache-busting file names for the outputs of
+	// Transform phase rules (CopyRule, CSSMinifier, ExternalTransform etc) and
+	// for co-located assets, eg css/app.css -> css/app.3f9a2c1e.css.
+	// Use the Asset template function to reference fingerprinted files.
+	// Files without a rule that are copied as is keep their names.
+	FingerprintAssets bool
+
+	// AssetManifestPath is the path (relative to OutputDir) where the asset
+	// manifest mapping original to fingerprinted paths is written.
+	// Defaults to "asset-manifest.json" if not set.
+	AssetManifestPath string
+
+	// assetManifest maps original output paths to fingerprinted paths, and
+	// oldAssetManifest holds the manifest of the last full build whose
+	// fingerprinted files are removed once they are replaced.
+	assetManifest    map[string]string
+	oldAssetManifest map[string]string
+
+	// buildCtx is the context of the build in progress (or the last build).
+	buildCtx *BuildContext
+
+	// sriCache holds SRI digests computed during the current build.
+	sriCache map[string]string
+
+	// imageSets holds the variants generated by ImageTransform, keyed by the
+	// full path of the source image.
+	imageSets map[string][]imageVariant
+
+	// imageInfos caches image metadata across builds, keyed by full path.
+	imageInfos map[string]*ImageInfo
+
+	// refIndex maps slugified page titles, slugs and names to pages for
+	// resolving references. Built lazily once per build.
+	refIndex map[string][]*Resource
+
+	// unresolvedRefs holds references that could not be resolved in the
+	// current build, keyed by the referring page.
+	unresolvedRefs map[string][]string
+
+	// brokenLinks holds the broken relative links already reported in the
+	// current build, keyed by the linking page.
+	brokenLinks map[string][]string
+
+	// resourceErrors holds the errors with resources already reported in the
+	// current build, keyed by resource.
+	resourceErrors map[string][]string
+
+	// pageLinks holds the links found in the content of the pages rendered
+	// in the current build, keyed by page. They are added to resedges as
+	// link edges once all pages are rendered.
+	pageLinks map[string][]string
+
+	// pageURLs maps the URL paths of generated pages to their source pages.
+	pageURLs map[string]string
+
+	// linkGraphReaders are the pages that used Backlinks or OutgoingLinks
+	// while rendering, and linkPassRenders the rule runs to repeat for them
+	// once the link graph is complete.
+	linkGraphReaders  map[string]bool
+	linkPassRenders   []pendingRender
+	renderingLinkPass bool
+
+	// redirects maps the aliases of pages to their redirects, see Redirects.
+	// Each build stores a new map so the server can read it while watching.
+	redirects atomic.Pointer[map[string]Redirect]
+
+	// pages are the content pages of the site, indexed once per build in the
+	// Discover phase. See indexPages.
+	pages []*Resource
+
+	// taxonomyTerms holds the terms of each taxonomy, keyed by taxonomy
+	// name. Built in the Discover phase.
+	taxonomyTerms map[string]*taxonomyTerms
+
+	// sections holds the section tree keyed by the dir of each section
+	// relative to ContentRoot ("" for the root), and series the series of
+	// pages keyed by slug. Built in the Discover phase.
+	sections map[string]*Section
+	series   map[string]*Series
+
+	// menus holds the entries of each menu from Menus and front matter.
+	// Built in the Discover phase.
+	menus map[string][]menuEntry
+
+	// related holds the related pages of each page, most related first.
+	// Built in the Discover phase.
+	related map[*Resource][]*Resource
 }
 
 // Init initializes the Site object with default values.
 func (s *Site) Init() *Site {
 	s.ContentRoot = gut.ExpandUserPath(s.ContentRoot)
+	s.DataDir = gut.ExpandUserPath(s.DataDir)
 	s.resourceInRule = map[string]map[Rule]bool{}
 	if len(s.BuildRules) == 0 {
 		// setup some defaults
@@ -198,6 +317,14 @@ func (s *Site) Init() *Site {
 		s.SharedAssetsDir = "_assets"
 	}
 
+	// Set default asset manifest path
+	if s.AssetManifestPath == "" {
+		s.AssetManifestPath = "asset-manifest.json"
+	}
+	if s.assetManifest == nil {
+		s.assetManifest = make(map[string]string)
+	}
+
 	// Migrate BuildRules to PhaseRules
 	if s.PhaseRules == nil {
 		s.PhaseRules = make(map[BuildPhase][]Rule)
@@ -225,6 +352,20 @@ func (s *Site) PathRelUrl(path string) string {
 	return s.PathPrefix + path
 }
 
+// TargetURL returns the URL a generated target is served at, eg
+// <OutputDir>/a/b/index.html is served at <PathPrefix>/a/b/.
+func (s *Site) TargetURL(target *Resource) string {
+	relpath, err := filepath.Rel(s.OutputDir, target.FullPath)
+	if err != nil {
+		return ""
+	}
+	relpath = filepath.ToSlash(relpath)
+	if relpath == "index.html" || strings.HasSuffix(relpath, "/index.html") {
+		relpath = strings.TrimSuffix(relpath, "index.html")
+	}
+	return s.PathRelUrl("/" + relpath)
+}
+
 // HandleStatic adds a new static path to the site's router.
 func (s *Site) HandleStatic(path, folder string) *Site {
 	s.StaticFolders = append(s.StaticFolders, path)
@@ -247,8 +388,9 @@ func (s *Site) Handler() http.Handler {
 
 		// Serve everything else from the
 
-		// Now add the file loader/handler for the "published" dir
-		s.mux.Handle("/", http.FileServer(http.Dir(s.OutputDir)))
+		// Now add the file loader/handler for the "published" dir, with
+		// aliases redirected to their pages
+		s.mux.Handle("/", s.redirectHandler(http.FileServer(http.Dir(s.OutputDir))))
 	}
 	return s.mux
 }
@@ -322,6 +464,18 @@ func (s *Site) ListResources(filterFunc ResourceFilterFunc,
 	return
 }
 
+// indexPages lists the content pages of the site (excluding assets and
+// parametric pages) and loads their bases, so indexes like taxonomies and
+// series are built without walking the content again.
+func (s *Site) indexPages() {
+	s.pages = s.ListResources(func(res *Resource) bool {
+		return res.AssetOf == nil && !isParametricPath(res.FullPath) && slices.Contains(contentExtensions, res.Ext())
+	}, nil, -1, -1)
+	s.pages = slices.DeleteFunc(s.pages, func(res *Resource) bool {
+		return s.resourceBase(res) == nil
+	})
+}
+
 // GenerateSitemap generates a sitemap for the site.
 func (s *Site) GenerateSitemap() map[string]any {
 	return nil
@@ -363,6 +517,13 @@ func (s *Site) Rebuild(rs []*Resource) {
 		CreatedInPhase: make(map[BuildPhase][]*Resource),
 		hooks:          s.Hooks,
 	}
+	s.buildCtx = ctx
+	s.sriCache = map[string]string{}
+	s.refIndex = nil
+	s.unresolvedRefs = nil
+	s.brokenLinks = nil
+	s.resourceErrors = nil
+	s.pageLinks = nil
 
 	// === PHASE: Discover ===
 	ctx.CurrentPhase = PhaseDiscover
@@ -370,13 +531,27 @@ func (s *Site) Rebuild(rs []*Resource) {
 	ctx.hooks.emitPhaseStart(ctx)
 
 	if rs == nil {
+		// A full rebuild processes every resource again
+		s.resourceInRule = map[string]map[Rule]bool{}
+		if s.FingerprintAssets {
+			s.resetAssetManifest()
+		}
 		rs = s.ListResources(nil, nil, 0, 0)
 	}
 
-	// Discover assets for each content resource
+	s.loadData(ctx)
+
+	// Discover assets for each content resource and read image metadata
 	for _, res := range rs {
 		s.discoverAssets(res)
+		s.loadAssetMetadata(res)
 	}
+	s.indexPages()
+	s.buildTaxonomies()
+	s.buildSections()
+	s.buildNavigation()
+	s.buildMenus(ctx)
+	s.buildRelated()
 
 	// Sort by priority
 	if s.PriorityFunc != nil {
@@ -399,11 +574,21 @@ func (s *Site) Rebuild(rs []*Resource) {
 	log.Printf("=== Phase: %s ===", ctx.CurrentPhase)
 	ctx.hooks.emitPhaseStart(ctx)
 	s.runPhase(ctx, PhaseGenerate)
+	s.renderTaxonomyPages(ctx)
+	s.buildLinkGraph(ctx)
+	s.renderLinkPass(ctx)
+	s.buildRedirects(ctx)
 	ctx.hooks.emitPhaseEnd(ctx)
 
 	// Handle resources that didn't match any rule (default behavior)
 	s.handleUnmatchedResources(ctx)
 
+	if s.FingerprintAssets {
+		if err := s.writeAssetManifest(); err != nil {
+			ctx.AddError(fmt.Errorf("asset manifest generation failed: %w", err))
+		}
+	}
+
 	// === PHASE: Finalize ===
 	ctx.CurrentPhase = PhaseFinalize
 	log.Printf("=== Phase: %s ===", ctx.CurrentPhase)
@@ -411,7 +596,13 @@ func (s *Site) Rebuild(rs []*Resource) {
 	s.runPhase(ctx, PhaseFinalize)
 	ctx.hooks.emitPhaseEnd(ctx)
 
-	// Report errors
+	// Report warnings and errors
+	if len(ctx.Warnings) > 0 {
+		log.Printf("Build completed with %d warnings", len(ctx.Warnings))
+		for _, err := range ctx.Warnings {
+			log.Printf("  - %v", err)
+		}
+	}
 	if len(ctx.Errors) > 0 {
 		log.Printf("Build completed with %d errors", len(ctx.Errors))
 		for _, err := range ctx.Errors {
@@ -427,7 +618,9 @@ func (s *Site) runPhase(ctx *BuildContext, phase BuildPhase) {
 
 	for _, res := range ctx.Resources {
 		// Skip assets - they're handled with their parent resource
-		if res.AssetOf != nil {
+		// (except by transform rules that process assets, eg image resizing)
+		isAsset := res.AssetOf != nil
+		if isAsset && phase != PhaseTransform {
 			continue
 		}
 
@@ -437,6 +630,12 @@ func (s *Site) runPhase(ctx *BuildContext, phase BuildPhase) {
 		}
 
 		for _, rule := range rules {
+			if isAsset {
+				if at, ok := rule.(AssetTransformRule); !ok || !at.TransformsAssets() {
+					continue
+				}
+			}
+
 			siblings, targets := rule.TargetsFor(s, res)
 			if len(targets) == 0 {
 				continue
@@ -464,6 +663,22 @@ func (s *Site) runPhase(ctx *BuildContext, phase BuildPhase) {
 				log.Printf("Error running rule for %s: %v", res.FullPath, err)
 				ctx.AddError(err)
 			} else {
+				// Fingerprint transformed assets so they can be cached indefinitely
+				if phase == PhaseTransform && s.FingerprintAssets {
+					for i, t := range targets {
+						fpres, err := s.fingerprintTarget(t)
+						if err != nil {
+							ctx.AddError(err)
+						}
+						targets[i] = fpres
+					}
+				}
+
+				// Pages that used the link graph are rendered again once it is complete
+				if phase == PhaseGenerate && s.linkGraphReaders[res.FullPath] {
+					s.linkPassRenders = append(s.linkPassRenders, pendingRender{res: res, rule: rule, inputs: inputs, targets: targets})
+				}
+
 				// Track generated targets
 				for _, t := range targets {
 					t.ProducedBy = rule
@@ -475,8 +690,8 @@ func (s *Site) runPhase(ctx *BuildContext, phase BuildPhase) {
 				ctx.hooks.emitResourceProcessed(ctx, res, targets)
 			}
 
-			// Parametric pages are fully handled by ParametricPages rule
-			if res.IsParametric {
+			// Parametric and paginated pages are fully handled by ParametricPages rule
+			if res.IsParametric || isPaginated(res) {
 				break
 			}
 		}
@@ -550,7 +765,7 @@ func (s *Site) resourceMatchedARule(res *Resource) bool {
 
 func stageFuncs(res *Resource) map[string]any {
 	localData := make(map[string]any)
-	return map[string]any{
+	funcs := map[string]any{
 		"StageSet": func(key string, value any, kvpairs ...any) any {
 			// log.Printf("Settin Key %s in resource %s", key, res.FullPath)
 			localData[key] = value
@@ -566,13 +781,9 @@ func stageFuncs(res *Resource) map[string]any {
 			// log.Printf("Gettin Key %s in resource %s", key, res.FullPath)
 			return localData[key]
 		},
-		// AssetURL returns the URL for a co-located asset file.
-		// For normal pages, returns relative path (./filename).
-		// For parametric pages, returns shared assets path (/_assets/hash/filename).
-		"AssetURL": func(filename string) string {
-			return GetAssetURL(res.Site, res, filename)
-		},
 	}
+	maps.Copy(funcs, resourceFuncs(res))
+	return funcs
 }
 
 func (s *Site) Serve(address string) error {
@@ -770,7 +981,14 @@ func (s *Site) processAssetMappings(mappings []AssetMapping) error {
 	for _, m := range mappings {
 		switch m.Action {
 		case AssetCopy:
-			destPath := filepath.Join(s.OutputDir, m.Dest)
+			dest := m.Dest
+			if s.FingerprintAssets {
+				if hash := ContentHashShort(m.Source); hash != "" {
+					dest = fingerprintedPath(m.Dest, hash)
+					s.recordFingerprint(m.Dest, dest)
+				}
+			}
+			destPath := filepath.Join(s.OutputDir, dest)
 			if err := s.copyAsset(m.Source, destPath); err != nil {
 				return err
 			}
@@ -0,0 +1,75 @@
+package s3gen
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSRIAlgorithm is the digest used by SRI when no algorithm is given.
+const DefaultSRIAlgorithm = "sha384"
+
+// SRI returns a Subresource Integrity string (eg "sha384-oqVuAf...") for a
+// built asset, ready to be used in an integrity= attribute. The path is
+// relative to the output directory and may be either the original or the
+// fingerprinted path; a leading "/" or PathPrefix is ignored. Supported
+// algorithms are "sha256", "sha384" and "sha512". Passing several returns a
+// space separated list of digests.
+//
+// Digests are computed from the generated output so SRI can only be used once
+// the Transform phase has produced the file. They are cached for the rest of
+// the build.
+func (s *Site) SRI(path string, algos ...string) (string, error) {
+	if s.buildCtx != nil && s.buildCtx.CurrentPhase <= PhaseTransform {
+		return "", fmt.Errorf("SRI(%s) called in %s phase, assets are only available after the Transform phase", path, s.buildCtx.CurrentPhase)
+	}
+	if len(algos) == 0 {
+		algos = []string{DefaultSRIAlgorithm}
+	}
+
+	relpath, _ := cutPathPrefix(filepath.ToSlash(path), s.PathPrefix)
+	relpath = strings.TrimPrefix(relpath, "/")
+	if fp, ok := s.fingerprintOf(relpath); ok {
+		relpath = fp
+	}
+
+	var digests []string
+	for _, algo := range algos {
+		key := algo + ":" + relpath
+		if digest, ok := s.sriCache[key]; ok {
+			digests = append(digests, digest)
+			continue
+		}
+
+		var h hash.Hash
+		switch algo {
+		case "sha256":
+			h = sha256.New()
+		case "sha384":
+			h = sha512.New384()
+		case "sha512":
+			h = sha512.New()
+		default:
+			return "", fmt.Errorf("SRI: unsupported algorithm %q", algo)
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.OutputDir, relpath))
+		if err != nil {
+			return "", fmt.Errorf("SRI: asset %s has not been built: %w", path, err)
+		}
+		h.Write(data)
+		digest := algo + "-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		if s.sriCache == nil {
+			s.sriCache = map[string]string{}
+		}
+		s.sriCache[key] = digest
+		digests = append(digests, digest)
+	}
+	return strings.Join(digests, " "), nil
+}
@@ -0,0 +1,358 @@
+package s3gen
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	gotl "github.com/panyam/goutils/template"
+)
+
+// Taxonomy is a way of classifying pages by a front matter field listing
+// the terms of each page, eg:
+//
+//	categories: [Programming, Go]
+//	authors: Jane Doe
+//
+// Sites declare their taxonomies in Site.Taxonomies. Each taxonomy gets an
+// index of terms (see the Terms, Term, TermsOf and PagesByTerm template
+// functions) and optionally generated pages listing its terms and the pages
+// of each term.
+type Taxonomy struct {
+	// Name is the front matter field holding the terms of a page, eg
+	// "categories"
+	Name string
+
+	// Path is the URL path of the taxonomy's pages (default: Name). The list
+	// of terms is at /<Path>/ and the pages of a term at /<Path>/<term slug>/.
+	Path string
+
+	// Aliases map alternate names of terms to their canonical names, eg
+	// {"golang": "Go"}. Terms are matched by slug, so "Go Lang" and "go-lang"
+	// are the same term.
+	Aliases map[string]string
+
+	// TermsTemplate is the template of the page listing the terms. The page
+	// is not generated if the template is not set. Its params are Site,
+	// Taxonomy and Terms (sorted by count).
+	TermsTemplate BaseTemplate
+
+	// TermTemplate is the template of the page of each term. The pages are
+	// not generated if the template is not set. Its params are Site,
+	// Taxonomy, Term and Paginator.
+	TermTemplate BaseTemplate
+
+	// PageSize is the number of pages listed on a term page. Term pages are
+	// paginated (at /<Path>/<term slug>/page/N/) when set.
+	PageSize int
+
+	// IncludeDrafts includes pages marked as drafts in the index
+	IncludeDrafts bool
+}
+
+// Term is a term of a taxonomy and the pages classified under it.
+type Term struct {
+	// Taxonomy is the name of the taxonomy
+	Taxonomy string
+
+	// Name is the canonical name of the term, eg "Go"
+	Name string
+
+	// Slug is the URL friendly name of the term, eg "go"
+	Slug string
+
+	// URL is the URL of the term's page, or empty if term pages are not
+	// generated
+	URL string
+
+	// Pages are the pages with this term, newest first
+	Pages []*Resource
+}
+
+// Count returns the number of pages with the term.
+func (t *Term) Count() int {
+	return len(t.Pages)
+}
+
+// taxonomyTerms holds the terms of a taxonomy keyed by slug.
+type taxonomyTerms struct {
+	taxonomy *Taxonomy
+
+	// aliases maps the slugs of aliases to canonical names
+	aliases map[string]string
+
+	terms map[string]*Term
+}
+
+// urlPath returns the path of the taxonomy's pages relative to the site.
+func (t *Taxonomy) urlPath() string {
+	if t.Path != "" {
+		return strings.Trim(t.Path, "/")
+	}
+	return t.Name
+}
+
+// termName returns the canonical name and slug of a term.
+func (tt *taxonomyTerms) termName(term string) (name, slug string) {
+	name = strings.TrimSpace(term)
+	if canonical, ok := tt.aliases[gotl.Slugify(name)]; ok {
+		name = canonical
+	}
+	return name, gotl.Slugify(name)
+}
+
+// pageTerms returns the terms of a page for a front matter field, which is
+// either a list or a single term.
+func pageTerms(fm map[string]any, field string) (out []string) {
+	switch val := fm[field].(type) {
+	case string:
+		out = append(out, val)
+	case []any:
+		for _, v := range val {
+			if v != nil {
+				out = append(out, fmt.Sprint(v))
+			}
+		}
+	case []string:
+		out = val
+	}
+	return
+}
+
+// buildTaxonomies indexes the terms of all pages for each taxonomy of the
+// site.
+func (s *Site) buildTaxonomies() {
+	s.taxonomyTerms = map[string]*taxonomyTerms{}
+	if len(s.Taxonomies) == 0 {
+		return
+	}
+	for i := range s.Taxonomies {
+		tx := &s.Taxonomies[i]
+		tt := &taxonomyTerms{taxonomy: tx, aliases: map[string]string{}, terms: map[string]*Term{}}
+		for alias, canonical := range tx.Aliases {
+			tt.aliases[gotl.Slugify(alias)] = canonical
+		}
+		s.taxonomyTerms[tx.Name] = tt
+	}
+
+	for _, res := range s.pages {
+		base := res.Base.(*DefaultResourceBase)
+		fm := res.FrontMatter().Data
+		for _, tt := range s.taxonomyTerms {
+			if base.IsDraft && !tt.taxonomy.IncludeDrafts {
+				continue
+			}
+			for _, value := range pageTerms(fm, tt.taxonomy.Name) {
+				name, slug := tt.termName(value)
+				if slug == "" {
+					continue
+				}
+				term := tt.terms[slug]
+				if term == nil {
+					term = &Term{Taxonomy: tt.taxonomy.Name, Name: name, Slug: slug}
+					if tt.taxonomy.TermTemplate.Name != "" {
+						term.URL = s.PathRelUrl("/" + path.Join(tt.taxonomy.urlPath(), slug) + "/")
+					}
+					tt.terms[slug] = term
+				}
+				if !slices.Contains(term.Pages, res) {
+					term.Pages = append(term.Pages, res)
+				}
+			}
+		}
+	}
+
+	for _, tt := range s.taxonomyTerms {
+		for _, term := range tt.terms {
+			sort.SliceStable(term.Pages, func(i, j int) bool {
+				return pageDate(term.Pages[j]).Before(pageDate(term.Pages[i]))
+			})
+		}
+	}
+}
+
+// Terms returns the terms of a taxonomy, with the most used terms first.
+func (s *Site) Terms(taxonomy string) (out []*Term) {
+	tt := s.taxonomyTerms[taxonomy]
+	if tt == nil {
+		return nil
+	}
+	for _, term := range tt.terms {
+		out = append(out, term)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count() != out[j].Count() {
+			return out[i].Count() > out[j].Count()
+		}
+		return out[i].Slug < out[j].Slug
+	})
+	return
+}
+
+// Term returns a term of a taxonomy by its name, slug or an alias, or nil if
+// no page has the term.
+func (s *Site) Term(taxonomy string, term string) *Term {
+	tt := s.taxonomyTerms[taxonomy]
+	if tt == nil {
+		return nil
+	}
+	_, slug := tt.termName(term)
+	return tt.terms[slug]
+}
+
+// TermsOf returns the terms of a page in a taxonomy, in the order the page
+// lists them.
+func (s *Site) TermsOf(res *Resource, taxonomy string) (out []*Term) {
+	tt := s.taxonomyTerms[taxonomy]
+	if tt == nil || res == nil {
+		return nil
+	}
+	for _, value := range pageTerms(res.FrontMatter().Data, taxonomy) {
+		_, slug := tt.termName(value)
+		if term := tt.terms[slug]; term != nil && !slices.Contains(out, term) {
+			out = append(out, term)
+		}
+	}
+	return
+}
+
+// PagesByTerm returns the pages with a term of a taxonomy, newest first.
+func (s *Site) PagesByTerm(taxonomy string, term string) []*Resource {
+	if t := s.Term(taxonomy, term); t != nil {
+		return t.Pages
+	}
+	return nil
+}
+
+// renderTaxonomyPages writes the terms page and the term pages of each
+// taxonomy with templates.
+func (s *Site) renderTaxonomyPages(ctx *BuildContext) {
+	for i := range s.Taxonomies {
+		tx := &s.Taxonomies[i]
+		dir := filepath.Join(s.OutputDir, filepath.FromSlash(tx.urlPath()))
+		source := s.taxonomySource(tx)
+		var targets []*Resource
+		render := func(outpath string, template BaseTemplate, params map[any]any, pager *Paginator) {
+			// Pages of this or earlier builds have a content file as their source
+			if other := s.resources[outpath]; other != nil && other.Source != nil && other.Source.FullPath != source.FullPath {
+				ctx.AddError(fmt.Errorf("%s page %s conflicts with the page generated from %s", tx.Name, outpath, other.Source.FullPath))
+				return
+			}
+			target := s.GetResource(outpath)
+			target.Source = source
+			target.Paginator = pager
+			if err := s.renderPage(target, template, params); err != nil {
+				ctx.AddError(fmt.Errorf("%s page %s failed: %w", tx.Name, outpath, err))
+				return
+			}
+			target.ProducedAt = PhaseGenerate
+			targets = append(targets, target)
+		}
+
+		if tx.TermsTemplate.Name != "" {
+			render(filepath.Join(dir, "index.html"), tx.TermsTemplate, map[any]any{
+				"Taxonomy": tx,
+				"Terms":    s.Terms(tx.Name),
+			}, nil)
+		}
+		if tx.TermTemplate.Name != "" {
+			for _, term := range s.Terms(tx.Name) {
+				for _, pager := range termPaginators(term, tx.PageSize) {
+					outpath := filepath.Join(dir, term.Slug, "index.html")
+					if pager.PageNumber > 1 {
+						outpath = filepath.Join(dir, term.Slug, "page", strconv.Itoa(pager.PageNumber), "index.html")
+					}
+					render(outpath, tx.TermTemplate, map[any]any{
+						"Taxonomy":  tx,
+						"Term":      term,
+						"Paginator": pager,
+					}, pager)
+				}
+			}
+		}
+
+		if len(targets) > 0 {
+			log.Printf("[Taxonomy] Wrote %d %s pages", len(targets), tx.Name)
+			for _, t := range targets {
+				ctx.AddTarget(t)
+			}
+			ctx.hooks.emitResourceProcessed(ctx, source, targets)
+		}
+	}
+}
+
+// taxonomySource returns the resource given to hooks as the source of a
+// taxonomy's pages, which have no content file. It stands for an _index.html
+// in the taxonomy's dir under ContentRoot and has empty front matter.
+func (s *Site) taxonomySource(tx *Taxonomy) *Resource {
+	return &Resource{
+		Site:        s,
+		FullPath:    filepath.Join(s.ContentRoot, filepath.FromSlash(tx.urlPath()), "_index.html"),
+		State:       ResourceStateLoaded,
+		frontMatter: FrontMatter{Loaded: true, Data: map[string]any{}},
+	}
+}
+
+// termPaginators splits the pages of a term into pages of size items (or a
+// single page if size is 0).
+func termPaginators(term *Term, size int) (out []*Paginator) {
+	if size <= 0 {
+		size = max(1, term.Count())
+	}
+	totalPages := max(1, (term.Count()+size-1)/size)
+	urls := make([]string, totalPages)
+	for n := 1; n <= totalPages; n++ {
+		urls[n-1] = term.URL
+		if n > 1 {
+			urls[n-1] = term.URL + "page/" + strconv.Itoa(n) + "/"
+		}
+	}
+	for n := 1; n <= totalPages; n++ {
+		pager := &Paginator{
+			Items:      term.Pages[min(term.Count(), (n-1)*size):min(term.Count(), n*size)],
+			PageNumber: n,
+			PageSize:   size,
+			TotalItems: term.Count(),
+			TotalPages: totalPages,
+			PageURLs:   urls,
+			FirstURL:   urls[0],
+			LastURL:    urls[totalPages-1],
+		}
+		if pager.HasPrev() {
+			pager.PrevURL = urls[n-2]
+		}
+		if pager.HasNext() {
+			pager.NextURL = urls[n]
+		}
+		out = append(out, pager)
+	}
+	return
+}
+
+// renderPage renders a template that is not backed by a content file (eg a
+// taxonomy page) to a target. Site is added to the params.
+func (s *Site) renderPage(target *Resource, template BaseTemplate, params map[any]any) error {
+	tmpl, err := s.Templates.Loader.Load(template.Name, "")
+	if err != nil {
+		return err
+	}
+	params["Site"] = s
+	if template.Params != nil {
+		maps.Copy(params, template.Params)
+	}
+
+	target.EnsureDir()
+	outfile, err := os.Create(target.FullPath)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+	return s.Templates.RenderHtmlTemplate(outfile, tmpl[0], template.Entry, params, nil)
+}
@@ -1,9 +1,30 @@
 package s3gen
 
 import (
+	"strings"
+
 	"github.com/morrisxyang/xreflect"
 )
 
 func setNestedProp(obj any, value any, fieldpath string) error {
 	return xreflect.SetEmbedField(obj, fieldpath, value)
 }
+
+// cutPathPrefix removes a URL path prefix (eg the PathPrefix "/blog") from a
+// path. The prefix only matches whole path segments, so "/blog/post" is
+// under "/blog" but "/blogroll" is not. Returns false if the path is not
+// under the prefix.
+func cutPathPrefix(p, prefix string) (string, bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return p, true
+	}
+	rest, ok := strings.CutPrefix(p, prefix)
+	if !ok || (rest != "" && rest[0] != '/') {
+		return p, false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
@@ -0,0 +1,27 @@
+package s3gen
+
+import "testing"
+
+func TestCutPathPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         string
+		ok           bool
+	}{
+		{"/blog/post/", "", "/blog/post/", true},
+		{"/blog/post/", "/", "/blog/post/", true},
+		{"/blog/post/", "/blog", "/post/", true},
+		{"/blog/post/", "/blog/", "/post/", true},
+		{"/blog", "/blog", "/", true},
+		{"/blog/", "/blog", "/", true},
+		{"/blogroll/", "/blog", "/blogroll/", false},
+		{"/docs/", "/blog", "/docs/", false},
+		{"/a/b/c", "/a/b", "/c", true},
+	}
+	for _, test := range tests {
+		got, ok := cutPathPrefix(test.path, test.prefix)
+		if got != test.want || ok != test.ok {
+			t.Errorf("cutPathPrefix(%q, %q) = %q, %v, want %q, %v", test.path, test.prefix, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -28,6 +28,9 @@ func (s *Site) Watch() {
 			defer tickerChan.Stop()
 
 			foundResources := make(map[string]*Resource)
+
+			// Any page can use data files so changes to them rebuild the site
+			dataChanged := false
 			for {
 				select {
 				case event := <-w.Event:
@@ -35,6 +38,11 @@ func (s *Site) Watch() {
 					log.Println("Collecting Event: ", event)
 
 					fullpath := event.Path
+					if s.isDataPath(fullpath) {
+						dataChanged = true
+						continue
+					}
+
 					info, err := os.Stat(fullpath)
 					if err != nil {
 						fmt.Println("Error with file: ", event.Path, err)
@@ -58,6 +66,14 @@ func (s *Site) Watch() {
 					// Stop building and uit
 					return
 				case <-tickerChan.C:
+					if dataChanged {
+						log.Println("data files changed, rebuilding site")
+						s.Rebuild(nil)
+						dataChanged = false
+						foundResources = make(map[string]*Resource)
+						break
+					}
+
 					// if we have things in the collected files - kick off a rebuild
 					if len(foundResources) > 0 {
 						log.Println("files collected so far: ", foundResources)
@@ -76,6 +92,15 @@ func (s *Site) Watch() {
 			log.Fatalln("Error adding files recursive: ", s.ContentRoot, err)
 		}
 
+		if s.DataDir != "" {
+			if _, err := os.Stat(s.DataDir); err == nil {
+				log.Println("Adding data files recursive: ", s.DataDir)
+				if err := w.AddRecursive(s.DataDir); err != nil {
+					log.Fatalln("Error adding data files recursive: ", s.DataDir, err)
+				}
+			}
+		}
+
 		// start the watching process
 		go func() {
 			log.Println("Starting watcher...")